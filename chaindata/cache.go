@@ -0,0 +1,225 @@
+package chaindata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/rs/zerolog/log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheSize = 10000
+	// Upstream lookups are detached from the request that started them, since other requests may wait for the result
+	upstreamTimeout = 30 * time.Second
+)
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryCache is an in-memory ChainDataCache that evicts the least recently used entries
+type MemoryCache struct {
+	entries lru.BasicLRU[string, memoryCacheEntry]
+	mu      sync.Mutex
+}
+
+func NewMemoryCache(size int) *MemoryCache {
+	if size <= 0 {
+		size = defaultCacheSize
+	}
+
+	return &MemoryCache{
+		entries: lru.NewBasicLRU[string, memoryCacheEntry](size),
+	}
+}
+
+func (m *MemoryCache) Get(key string, _ context.Context) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expires) {
+		m.entries.Remove(key)
+		return nil, false, nil
+	}
+
+	return entry.value, true, nil
+}
+
+func (m *MemoryCache) Set(key string, value []byte, ttl time.Duration, _ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries.Add(key, memoryCacheEntry{value: value, expires: time.Now().Add(ttl)})
+	return nil
+}
+
+type flight struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// flightGroup coalesces concurrent lookups of the same key into a single upstream call
+type flightGroup struct {
+	flights map[string]*flight
+	mu      sync.Mutex
+}
+
+func (g *flightGroup) do(key string, fn func(ctx context.Context) ([]byte, error), ctx context.Context) ([]byte, error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		g.flights[key] = f
+		go g.run(key, f, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(key string, f *flight, fn func(ctx context.Context) ([]byte, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamTimeout)
+	defer cancel()
+
+	f.value, f.err = fn(ctx)
+
+	g.mu.Lock()
+	delete(g.flights, key)
+	g.mu.Unlock()
+
+	close(f.done)
+}
+
+// CachingProvider serves repeated lookups from a ChainDataCache and coalesces concurrent identical lookups.
+// Failed lookups are not cached.
+type CachingProvider struct {
+	provider common.ChainDataProvider
+	cache    common.ChainDataCache
+	cfg      config.ChainDataCacheConfig
+	group    flightGroup
+}
+
+// WithCache wraps the provider according to the config, or returns it unchanged if caching is disabled
+func WithCache(provider common.ChainDataProvider, cfg config.ChainDataCacheConfig) common.ChainDataProvider {
+	if !cfg.Enabled() {
+		return provider
+	}
+
+	return NewCachingProvider(provider, NewMemoryCache(cfg.Size), cfg)
+}
+
+func NewCachingProvider(provider common.ChainDataProvider, cache common.ChainDataCache, cfg config.ChainDataCacheConfig) *CachingProvider {
+	return &CachingProvider{
+		provider: provider,
+		cache:    cache,
+		cfg:      cfg,
+		group:    flightGroup{flights: make(map[string]*flight)},
+	}
+}
+
+func (c *CachingProvider) GetBalance(address, chain string, ctx context.Context) (string, error) {
+	return cached(c, config.EndpointBalance, cacheKey(config.EndpointBalance, address, chain, models.PageFilter{}), func(ctx context.Context) (string, error) {
+		return c.provider.GetBalance(address, chain, ctx)
+	}, ctx)
+}
+
+func (c *CachingProvider) GetTransactions(address, chain string, filter models.PageFilter, ctx context.Context) (models.TransactionPage, error) {
+	return cached(c, config.EndpointTransactions, cacheKey(config.EndpointTransactions, address, chain, filter), func(ctx context.Context) (models.TransactionPage, error) {
+		return c.provider.GetTransactions(address, chain, filter, ctx)
+	}, ctx)
+}
+
+func (c *CachingProvider) GetTokenBalances(address, chain string, ctx context.Context) ([]models.TokenBalance, error) {
+	return cached(c, config.EndpointTokenBalances, cacheKey(config.EndpointTokenBalances, address, chain, models.PageFilter{}), func(ctx context.Context) ([]models.TokenBalance, error) {
+		return c.provider.GetTokenBalances(address, chain, ctx)
+	}, ctx)
+}
+
+func (c *CachingProvider) GetTokenTransfers(address, chain string, filter models.PageFilter, ctx context.Context) (models.TokenTransferPage, error) {
+	return cached(c, config.EndpointTokenTransfers, cacheKey(config.EndpointTokenTransfers, address, chain, filter), func(ctx context.Context) (models.TokenTransferPage, error) {
+		return c.provider.GetTokenTransfers(address, chain, filter, ctx)
+	}, ctx)
+}
+
+func (c *CachingProvider) GetNFTs(address, chain string, filter models.PageFilter, ctx context.Context) (models.NFTPage, error) {
+	return cached(c, config.EndpointNFTs, cacheKey(config.EndpointNFTs, address, chain, filter), func(ctx context.Context) (models.NFTPage, error) {
+		return c.provider.GetNFTs(address, chain, filter, ctx)
+	}, ctx)
+}
+
+// cached returns the cached result of the lookup or performs it. Cache failures only cost the upstream call.
+func cached[T any](c *CachingProvider, endpoint, key string, lookup func(ctx context.Context) (T, error), ctx context.Context) (T, error) {
+	var result T
+
+	data, ok, err := c.cache.Get(key, ctx)
+	if err != nil {
+		log.Warn().Caller().Err(err).Str("key", key).Msg("failed to read chain data cache")
+	}
+	if ok {
+		if err := json.Unmarshal(data, &result); err == nil {
+			return result, nil
+		}
+	}
+
+	data, err = c.group.do(key, func(ctx context.Context) ([]byte, error) {
+		value, err := lookup(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", endpoint, err)
+		}
+
+		if err := c.cache.Set(key, data, c.cfg.TTL(endpoint), ctx); err != nil {
+			log.Warn().Caller().Err(err).Str("key", key).Msg("failed to write chain data cache")
+		}
+
+		return data, nil
+	}, ctx)
+	if err != nil {
+		return result, err
+	}
+
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return result, fmt.Errorf("failed to decode %s: %w", endpoint, err)
+	}
+
+	return result, nil
+}
+
+// cacheKey identifies a lookup by its endpoint, address, chain and every page filter that changes the result
+func cacheKey(endpoint, address, chain string, filter models.PageFilter) string {
+	key := []string{endpoint, chain, strings.ToLower(address), filter.Cursor, fmt.Sprint(filter.Limit), fmt.Sprint(filter.FromBlock), fmt.Sprint(filter.ToBlock)}
+	if !filter.FromDate.IsZero() {
+		key = append(key, filter.FromDate.UTC().Format(time.RFC3339))
+	} else {
+		key = append(key, "")
+	}
+	if !filter.ToDate.IsZero() {
+		key = append(key, filter.ToDate.UTC().Format(time.RFC3339))
+	} else {
+		key = append(key, "")
+	}
+	return strings.Join(key, "|")
+}
@@ -0,0 +1,253 @@
+package chaindata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	moralisBaseURL = "https://deep-index.moralis.io/api/v2"
+)
+
+type moralisErrorResponse struct {
+	Message string `json:"message"`
+}
+
+type MoralisProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+func NewMoralisProvider(apiKey string) *MoralisProvider {
+	return &MoralisProvider{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (m *MoralisProvider) GetBalance(address, chain string, ctx context.Context) (string, error) {
+	type response struct {
+		Balance string `json:"balance"`
+	}
+
+	query := url.Values{}
+	query.Set("chain", chain)
+
+	var res response
+	err := m.fetch(fmt.Sprintf("/%s/balance", address), query, &res, ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return res.Balance, nil
+}
+
+func (m *MoralisProvider) GetTransactions(address, chain string, filter models.PageFilter, ctx context.Context) (models.TransactionPage, error) {
+	type response struct {
+		Total    int64                `json:"total"`
+		PageSize int64                `json:"page_size"`
+		Page     int64                `json:"page"`
+		Cursor   string               `json:"cursor"`
+		Result   []models.Transaction `json:"result"`
+	}
+
+	query := url.Values{}
+	query.Set("chain", chain)
+	query.Set("disable_total", "true")
+	applyPageFilter(query, filter)
+
+	var res response
+	err := m.fetch(fmt.Sprintf("/%s", address), query, &res, ctx)
+	if err != nil {
+		return models.TransactionPage{}, err
+	}
+
+	return models.TransactionPage{
+		Transactions: res.Result,
+		Total:        res.Total,
+		Cursor:       res.Cursor,
+	}, nil
+}
+
+func (m *MoralisProvider) GetTokenBalances(address, chain string, ctx context.Context) ([]models.TokenBalance, error) {
+	query := url.Values{}
+	query.Set("chain", chain)
+
+	balances := make([]models.TokenBalance, 0)
+	err := m.fetch(fmt.Sprintf("/%s/erc20", address), query, &balances, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+func (m *MoralisProvider) GetTokenTransfers(address, chain string, filter models.PageFilter, ctx context.Context) (models.TokenTransferPage, error) {
+	type transfer struct {
+		TransactionHash  string `json:"transaction_hash"`
+		Address          string `json:"address"`
+		TokenName        string `json:"token_name"`
+		TokenSymbol      string `json:"token_symbol"`
+		TokenLogo        string `json:"token_logo"`
+		TokenDecimals    string `json:"token_decimals"`
+		FromAddress      string `json:"from_address"`
+		ToAddress        string `json:"to_address"`
+		Value            string `json:"value"`
+		BlockTimestamp   string `json:"block_timestamp"`
+		BlockNumber      string `json:"block_number"`
+		BlockHash        string `json:"block_hash"`
+		TransactionIndex int64  `json:"transaction_index"`
+		LogIndex         int64  `json:"log_index"`
+	}
+
+	type response struct {
+		Cursor string     `json:"cursor"`
+		Result []transfer `json:"result"`
+	}
+
+	query := url.Values{}
+	query.Set("chain", chain)
+	applyPageFilter(query, filter)
+
+	var res response
+	err := m.fetch(fmt.Sprintf("/%s/erc20/transfers", address), query, &res, ctx)
+	if err != nil {
+		return models.TokenTransferPage{}, err
+	}
+
+	transfers := make([]models.TokenTransfer, len(res.Result))
+	for i, t := range res.Result {
+		decimals, _ := strconv.ParseInt(t.TokenDecimals, 10, 64)
+		transfers[i] = models.TokenTransfer{
+			TransactionHash:  t.TransactionHash,
+			TokenAddress:     t.Address,
+			TokenName:        t.TokenName,
+			TokenSymbol:      t.TokenSymbol,
+			TokenLogo:        t.TokenLogo,
+			TokenDecimals:    decimals,
+			FromAddress:      t.FromAddress,
+			ToAddress:        t.ToAddress,
+			Value:            t.Value,
+			BlockTimestamp:   t.BlockTimestamp,
+			BlockNumber:      t.BlockNumber,
+			BlockHash:        t.BlockHash,
+			TransactionIndex: t.TransactionIndex,
+			LogIndex:         t.LogIndex,
+		}
+	}
+
+	return models.TokenTransferPage{
+		Transfers: transfers,
+		Cursor:    res.Cursor,
+	}, nil
+}
+
+func (m *MoralisProvider) GetNFTs(address, chain string, filter models.PageFilter, ctx context.Context) (models.NFTPage, error) {
+	type nft struct {
+		TokenAddress string `json:"token_address"`
+		TokenID      string `json:"token_id"`
+		Amount       string `json:"amount"`
+		ContractType string `json:"contract_type"`
+		Name         string `json:"name"`
+		Symbol       string `json:"symbol"`
+		TokenURI     string `json:"token_uri"`
+		Metadata     string `json:"metadata"`
+	}
+
+	type response struct {
+		Cursor string `json:"cursor"`
+		Result []nft  `json:"result"`
+	}
+
+	query := url.Values{}
+	query.Set("chain", chain)
+	query.Set("format", "decimal")
+	applyPageFilter(query, filter)
+
+	var res response
+	err := m.fetch(fmt.Sprintf("/%s/nft", address), query, &res, ctx)
+	if err != nil {
+		return models.NFTPage{}, err
+	}
+
+	nfts := make([]models.NFT, len(res.Result))
+	for i, n := range res.Result {
+		name, image := parseNFTMetadata([]byte(n.Metadata))
+		nfts[i] = models.NFT{
+			TokenAddress:     n.TokenAddress,
+			TokenID:          n.TokenID,
+			Amount:           n.Amount,
+			ContractType:     n.ContractType,
+			CollectionName:   n.Name,
+			CollectionSymbol: n.Symbol,
+			TokenURI:         n.TokenURI,
+			Name:             name,
+			Image:            image,
+		}
+	}
+
+	return models.NFTPage{
+		NFTs:   nfts,
+		Cursor: res.Cursor,
+	}, nil
+}
+
+func applyPageFilter(query url.Values, filter models.PageFilter) {
+	if filter.Cursor != "" {
+		query.Set("cursor", filter.Cursor)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(filter.Limit, 10))
+	}
+	if filter.FromBlock > 0 {
+		query.Set("from_block", strconv.FormatUint(filter.FromBlock, 10))
+	}
+	if filter.ToBlock > 0 {
+		query.Set("to_block", strconv.FormatUint(filter.ToBlock, 10))
+	}
+	if !filter.FromDate.IsZero() {
+		query.Set("from_date", filter.FromDate.UTC().Format(time.RFC3339))
+	}
+	if !filter.ToDate.IsZero() {
+		query.Set("to_date", filter.ToDate.UTC().Format(time.RFC3339))
+	}
+}
+
+func (m *MoralisProvider) fetch(path string, query url.Values, out any, ctx context.Context) error {
+	moralisURL := fmt.Sprintf("%s%s?%s", moralisBaseURL, path, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, moralisURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate request: %w", err)
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("X-API-Key", m.apiKey)
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode moralis response: %w", err)
+		}
+		return nil
+	}
+
+	var response moralisErrorResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode moralis response: %w", err)
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, response.Message)
+}
@@ -0,0 +1,288 @@
+package chaindata
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/ethutil"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/labstack/echo/v4"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	contractTypeERC721   = "ERC721"
+	contractTypeERC1155  = "ERC1155"
+	base64JSONDataPrefix = "data:application/json;base64,"
+	plainJSONDataPrefix  = "data:application/json,"
+	erc721ABIJSON        = `[
+		{"constant":true,"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"type":"function"},
+		{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"type":"function"},
+		{"constant":true,"inputs":[{"name":"tokenId","type":"uint256"}],"name":"ownerOf","outputs":[{"name":"","type":"address"}],"type":"function"},
+		{"constant":true,"inputs":[{"name":"tokenId","type":"uint256"}],"name":"tokenURI","outputs":[{"name":"","type":"string"}],"type":"function"}
+	]`
+	erc1155ABIJSON = `[
+		{"constant":true,"inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"type":"function"},
+		{"constant":true,"inputs":[{"name":"id","type":"uint256"}],"name":"uri","outputs":[{"name":"","type":"string"}],"type":"function"},
+		{"anonymous":false,"inputs":[{"indexed":true,"name":"operator","type":"address"},{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"ids","type":"uint256[]"},{"indexed":false,"name":"values","type":"uint256[]"}],"name":"TransferBatch","type":"event"}
+	]`
+)
+
+var (
+	erc721ABI           = ethutil.MustParseABI(erc721ABIJSON)
+	erc1155ABI          = ethutil.MustParseABI(erc1155ABIJSON)
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	transferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+type nftCandidate struct {
+	contract     common.Address
+	tokenID      *big.Int
+	contractType string
+}
+
+// GetNFTs discovers ERC-721 and ERC-1155 tokens received within the scanned block window and keeps those still held.
+// The returned cursor is the offset of the next page.
+func (r *RPCProvider) GetNFTs(address, _ string, filter models.PageFilter, ctx context.Context) (models.NFTPage, error) {
+	client, err := r.dial(ctx)
+	if err != nil {
+		return models.NFTPage{}, err
+	}
+
+	offset := 0
+	if filter.Cursor != "" {
+		offset, err = strconv.Atoi(filter.Cursor)
+		if err != nil || offset < 0 {
+			return models.NFTPage{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
+	}
+
+	limit := int(filter.Limit)
+	if limit <= 0 {
+		limit = rpcPageSize
+	}
+
+	owner := common.HexToAddress(address)
+	candidates, err := findNFTCandidates(client, owner, ctx)
+	if err != nil {
+		return models.NFTPage{}, err
+	}
+
+	held := make([]models.NFT, 0)
+	collections := make(map[common.Address][2]string)
+	for _, candidate := range candidates {
+		nft, ok := getHeldNFT(client, candidate, owner, ctx)
+		if !ok {
+			continue
+		}
+
+		if candidate.contractType == contractTypeERC721 {
+			collection, ok := collections[candidate.contract]
+			if !ok {
+				_ = callContract(client, erc721ABI, candidate.contract, "name", &collection[0], ctx)
+				_ = callContract(client, erc721ABI, candidate.contract, "symbol", &collection[1], ctx)
+				collections[candidate.contract] = collection
+			}
+			nft.CollectionName = collection[0]
+			nft.CollectionSymbol = collection[1]
+		}
+
+		held = append(held, nft)
+	}
+
+	if offset >= len(held) {
+		return models.NFTPage{NFTs: make([]models.NFT, 0)}, nil
+	}
+
+	end := offset + limit
+	cursor := ""
+	if end < len(held) {
+		cursor = strconv.Itoa(end)
+	} else {
+		end = len(held)
+	}
+
+	return models.NFTPage{
+		NFTs:   held[offset:end],
+		Cursor: cursor,
+	}, nil
+}
+
+func findNFTCandidates(client *ethclient.Client, owner common.Address, ctx context.Context) ([]nftCandidate, error) {
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	fromBlock := uint64(0)
+	if head > rpcHistoryDepth {
+		fromBlock = head - rpcHistoryDepth
+	}
+
+	ownerTopic := common.BytesToHash(owner.Bytes())
+	seen := make(map[string]struct{})
+	candidates := make([]nftCandidate, 0)
+
+	add := func(contract common.Address, tokenID *big.Int, contractType string) {
+		key := contract.Hex() + tokenID.String()
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		candidates = append(candidates, nftCandidate{contract, tokenID, contractType})
+	}
+
+	erc721Logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(head),
+		Topics:    [][]common.Hash{{transferTopic}, nil, {ownerTopic}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter erc721 logs: %w", err)
+	}
+	for _, l := range erc721Logs {
+		// ERC-20 transfers share the event signature but do not index the value
+		if len(l.Topics) != 4 || l.Removed {
+			continue
+		}
+		add(l.Address, l.Topics[3].Big(), contractTypeERC721)
+	}
+
+	erc1155Logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(head),
+		Topics:    [][]common.Hash{{transferSingleTopic, transferBatchTopic}, nil, nil, {ownerTopic}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter erc1155 logs: %w", err)
+	}
+	for _, l := range erc1155Logs {
+		if l.Removed {
+			continue
+		}
+		for _, id := range erc1155TokenIDs(l) {
+			add(l.Address, id, contractTypeERC1155)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].contract != candidates[j].contract {
+			return bytes.Compare(candidates[i].contract.Bytes(), candidates[j].contract.Bytes()) < 0
+		}
+		return candidates[i].tokenID.Cmp(candidates[j].tokenID) < 0
+	})
+
+	return candidates, nil
+}
+
+func erc1155TokenIDs(l types.Log) []*big.Int {
+	if l.Topics[0] == transferSingleTopic {
+		if len(l.Data) < 32 {
+			return nil
+		}
+		return []*big.Int{new(big.Int).SetBytes(l.Data[:32])}
+	}
+
+	values, err := erc1155ABI.Unpack("TransferBatch", l.Data)
+	if err != nil || len(values) != 2 {
+		return nil
+	}
+
+	ids, ok := values[0].([]*big.Int)
+	if !ok {
+		return nil
+	}
+
+	return ids
+}
+
+// getHeldNFT returns the token if the owner still holds it
+func getHeldNFT(client *ethclient.Client, candidate nftCandidate, owner common.Address, ctx context.Context) (models.NFT, bool) {
+	nft := models.NFT{
+		TokenAddress: strings.ToLower(candidate.contract.Hex()),
+		TokenID:      candidate.tokenID.String(),
+		ContractType: candidate.contractType,
+	}
+
+	switch candidate.contractType {
+	case contractTypeERC721:
+		var currentOwner common.Address
+		err := callContract(client, erc721ABI, candidate.contract, "ownerOf", &currentOwner, ctx, candidate.tokenID)
+		if err != nil || currentOwner != owner {
+			return models.NFT{}, false
+		}
+		nft.Amount = "1"
+		_ = callContract(client, erc721ABI, candidate.contract, "tokenURI", &nft.TokenURI, ctx, candidate.tokenID)
+	case contractTypeERC1155:
+		var balance *big.Int
+		err := callContract(client, erc1155ABI, candidate.contract, "balanceOf", &balance, ctx, owner, candidate.tokenID)
+		if err != nil || balance.Sign() <= 0 {
+			return models.NFT{}, false
+		}
+		nft.Amount = balance.String()
+		_ = callContract(client, erc1155ABI, candidate.contract, "uri", &nft.TokenURI, ctx, candidate.tokenID)
+		// See https://eips.ethereum.org/EIPS/eip-1155#metadata for the id substitution rules
+		nft.TokenURI = strings.ReplaceAll(nft.TokenURI, "{id}", fmt.Sprintf("%064x", candidate.tokenID))
+	}
+
+	nft.Name, nft.Image = parseNFTMetadata(inlineMetadata(nft.TokenURI))
+
+	return nft, true
+}
+
+// inlineMetadata returns the metadata embedded in a data URI.
+// Remote token URIs are deliberately not fetched, since they point to arbitrary hosts chosen by the contract.
+func inlineMetadata(tokenURI string) []byte {
+	switch {
+	case strings.HasPrefix(tokenURI, base64JSONDataPrefix):
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(tokenURI, base64JSONDataPrefix))
+		if err != nil {
+			return nil
+		}
+		return data
+	case strings.HasPrefix(tokenURI, plainJSONDataPrefix):
+		data, err := url.PathUnescape(strings.TrimPrefix(tokenURI, plainJSONDataPrefix))
+		if err != nil {
+			return nil
+		}
+		return []byte(data)
+	default:
+		return nil
+	}
+}
+
+// parseNFTMetadata extracts name and image from ERC-721/ERC-1155 metadata JSON
+func parseNFTMetadata(metadata []byte) (string, string) {
+	type parsed struct {
+		Name     string `json:"name"`
+		Image    string `json:"image"`
+		ImageURL string `json:"image_url"`
+	}
+
+	if len(metadata) == 0 {
+		return "", ""
+	}
+
+	var p parsed
+	if err := json.Unmarshal(metadata, &p); err != nil {
+		return "", ""
+	}
+
+	if p.Image == "" {
+		p.Image = p.ImageURL
+	}
+
+	return p.Name, p.Image
+}
@@ -0,0 +1,80 @@
+package chaindata
+
+import (
+	"context"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+)
+
+// Router dispatches every lookup to the ChainDataProvider configured for the requested chain.
+// Chains are identified by their hex id, see config.ChainConfig.HexID.
+type Router struct {
+	providers map[string]common.ChainDataProvider
+}
+
+func NewRouter(cfg config.Config) *Router {
+	moralis := NewMoralisProvider(cfg.MoralisApiKey)
+	r := &Router{
+		providers: make(map[string]common.ChainDataProvider, len(cfg.Chains)),
+	}
+
+	for _, chain := range cfg.Chains {
+		switch chain.DataProvider {
+		case config.DataProviderRPC:
+			r.providers[chain.HexID()] = NewRPCProvider(chain.RPCURL)
+		default:
+			r.providers[chain.HexID()] = moralis
+		}
+	}
+
+	return r
+}
+
+func (r *Router) GetBalance(address, chain string, ctx context.Context) (string, error) {
+	p, err := r.provider(chain)
+	if err != nil {
+		return "", err
+	}
+	return p.GetBalance(address, chain, ctx)
+}
+
+func (r *Router) GetTransactions(address, chain string, filter models.PageFilter, ctx context.Context) (models.TransactionPage, error) {
+	p, err := r.provider(chain)
+	if err != nil {
+		return models.TransactionPage{}, err
+	}
+	return p.GetTransactions(address, chain, filter, ctx)
+}
+
+func (r *Router) GetTokenBalances(address, chain string, ctx context.Context) ([]models.TokenBalance, error) {
+	p, err := r.provider(chain)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetTokenBalances(address, chain, ctx)
+}
+
+func (r *Router) GetTokenTransfers(address, chain string, filter models.PageFilter, ctx context.Context) (models.TokenTransferPage, error) {
+	p, err := r.provider(chain)
+	if err != nil {
+		return models.TokenTransferPage{}, err
+	}
+	return p.GetTokenTransfers(address, chain, filter, ctx)
+}
+
+func (r *Router) GetNFTs(address, chain string, filter models.PageFilter, ctx context.Context) (models.NFTPage, error) {
+	p, err := r.provider(chain)
+	if err != nil {
+		return models.NFTPage{}, err
+	}
+	return p.GetNFTs(address, chain, filter, ctx)
+}
+
+func (r *Router) provider(chain string) (common.ChainDataProvider, error) {
+	p, ok := r.providers[chain]
+	if !ok {
+		return nil, common.ErrUnsupportedChain
+	}
+	return p, nil
+}
@@ -0,0 +1,461 @@
+package chaindata
+
+import (
+	"context"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/ethutil"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/labstack/echo/v4"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rpcHistoryDepth is the number of most recent blocks scanned for transactions and token transfers.
+	// A plain node has no address index, so this provider is meant for dev chains like anvil or geth --dev.
+	rpcHistoryDepth = 1000
+	rpcPageSize     = 50
+	erc20ABIJSON    = `[
+		{"constant":true,"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"type":"function"},
+		{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"type":"function"},
+		{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"type":"function"},
+		{"constant":true,"inputs":[{"name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"type":"function"}
+	]`
+)
+
+var (
+	erc20ABI      = ethutil.MustParseABI(erc20ABIJSON)
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// RPCProvider serves chain data from any EVM JSON-RPC node
+type RPCProvider struct {
+	url    string
+	client *ethclient.Client
+	mu     sync.Mutex
+}
+
+func NewRPCProvider(url string) *RPCProvider {
+	return &RPCProvider{
+		url: url,
+	}
+}
+
+func (r *RPCProvider) GetBalance(address, _ string, ctx context.Context) (string, error) {
+	client, err := r.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	balance, err := client.BalanceAt(ctx, common.HexToAddress(address), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	return balance.String(), nil
+}
+
+// GetTransactions scans blocks from newest to oldest. The returned cursor is the number of the next block to scan.
+func (r *RPCProvider) GetTransactions(address, _ string, filter models.PageFilter, ctx context.Context) (models.TransactionPage, error) {
+	client, err := r.dial(ctx)
+	if err != nil {
+		return models.TransactionPage{}, err
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return models.TransactionPage{}, fmt.Errorf("failed to get chain id: %w", err)
+	}
+	signer := types.LatestSignerForChainID(chainID)
+
+	start, end, err := r.blockRange(client, filter, ctx)
+	if err != nil {
+		return models.TransactionPage{}, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = rpcPageSize
+	}
+
+	addr := common.HexToAddress(address)
+	transactions := make([]models.Transaction, 0)
+	cursor := ""
+	reachedFromDate := false
+
+	for number := start; number >= end; number-- {
+		if int64(len(transactions)) >= limit {
+			cursor = strconv.FormatUint(number, 10)
+			break
+		}
+
+		block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return models.TransactionPage{}, fmt.Errorf("failed to get block: %w", err)
+		}
+
+		blockTime := time.Unix(int64(block.Time()), 0)
+		if !filter.FromDate.IsZero() && blockTime.Before(filter.FromDate) {
+			reachedFromDate = true
+			break
+		}
+		if !filter.ToDate.IsZero() && blockTime.After(filter.ToDate) {
+			if number == 0 {
+				break
+			}
+			continue
+		}
+
+		for index, tx := range block.Transactions() {
+			from, err := types.Sender(signer, tx)
+			if err != nil {
+				continue
+			}
+			if from != addr && (tx.To() == nil || *tx.To() != addr) {
+				continue
+			}
+
+			receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return models.TransactionPage{}, fmt.Errorf("failed to get receipt: %w", err)
+			}
+
+			transactions = append(transactions, mapRPCTransaction(tx, from, index, block, receipt))
+		}
+
+		if number == 0 {
+			break
+		}
+	}
+
+	// The scan window was exhausted before the page was full, but older blocks may still match
+	if cursor == "" && !reachedFromDate && end > filter.FromBlock && end > 0 {
+		cursor = strconv.FormatUint(end-1, 10)
+	}
+
+	return models.TransactionPage{
+		Transactions: transactions,
+		Total:        int64(len(transactions)),
+		Cursor:       cursor,
+	}, nil
+}
+
+// blockRange returns the first (newest) and last (oldest) block to scan, limited to rpcHistoryDepth blocks per request
+func (r *RPCProvider) blockRange(client *ethclient.Client, filter models.PageFilter, ctx context.Context) (uint64, uint64, error) {
+	start, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	if filter.ToBlock > 0 && filter.ToBlock < start {
+		start = filter.ToBlock
+	}
+
+	if filter.Cursor != "" {
+		next, err := strconv.ParseUint(filter.Cursor, 10, 64)
+		if err != nil {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
+		if next < start {
+			start = next
+		}
+	}
+
+	end := filter.FromBlock
+	if start >= rpcHistoryDepth && start-rpcHistoryDepth+1 > end {
+		end = start - rpcHistoryDepth + 1
+	}
+
+	return start, end, nil
+}
+
+func (r *RPCProvider) GetTokenBalances(address, _ string, ctx context.Context) ([]models.TokenBalance, error) {
+	client, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	fromBlock := uint64(0)
+	if head > rpcHistoryDepth {
+		fromBlock = head - rpcHistoryDepth
+	}
+
+	addr := common.HexToAddress(address)
+
+	// Tokens are discovered through Transfer events, since there is no token index on a plain node
+	logs, err := filterTransferLogs(client, addr, fromBlock, head, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[common.Address]struct{})
+	for _, l := range logs {
+		tokens[l.Address] = struct{}{}
+	}
+
+	balances := make([]models.TokenBalance, 0, len(tokens))
+	for token := range tokens {
+		balance, err := getTokenBalance(client, token, addr, ctx)
+		if err != nil {
+			// Contracts emitting Transfer events are not necessarily ERC-20 compliant
+			continue
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
+// GetTokenTransfers returns ERC-20 transfers from newest to oldest. The returned cursor is the number of the next block to scan.
+func (r *RPCProvider) GetTokenTransfers(address, _ string, filter models.PageFilter, ctx context.Context) (models.TokenTransferPage, error) {
+	client, err := r.dial(ctx)
+	if err != nil {
+		return models.TokenTransferPage{}, err
+	}
+
+	start, end, err := r.blockRange(client, filter, ctx)
+	if err != nil {
+		return models.TokenTransferPage{}, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = rpcPageSize
+	}
+
+	logs, err := filterTransferLogs(client, common.HexToAddress(address), end, start, ctx)
+	if err != nil {
+		return models.TokenTransferPage{}, err
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber > logs[j].BlockNumber
+		}
+		return logs[i].Index > logs[j].Index
+	})
+
+	tokens := make(map[common.Address]models.TokenBalance)
+	blockTimes := make(map[uint64]time.Time)
+	transfers := make([]models.TokenTransfer, 0)
+	cursor := ""
+	lastBlock := uint64(0)
+	reachedFromDate := false
+
+	for _, l := range logs {
+		// Only complete blocks are returned, so that the cursor never splits a block
+		if int64(len(transfers)) >= limit && l.BlockNumber != lastBlock {
+			cursor = strconv.FormatUint(l.BlockNumber, 10)
+			break
+		}
+
+		blockTime, ok := blockTimes[l.BlockNumber]
+		if !ok {
+			header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(l.BlockNumber))
+			if err != nil {
+				return models.TokenTransferPage{}, fmt.Errorf("failed to get header: %w", err)
+			}
+			blockTime = time.Unix(int64(header.Time), 0)
+			blockTimes[l.BlockNumber] = blockTime
+		}
+
+		if !filter.FromDate.IsZero() && blockTime.Before(filter.FromDate) {
+			reachedFromDate = true
+			break
+		}
+		if !filter.ToDate.IsZero() && blockTime.After(filter.ToDate) {
+			continue
+		}
+
+		token, ok := tokens[l.Address]
+		if !ok {
+			token, err = getTokenMetadata(client, l.Address, ctx)
+			if err != nil {
+				continue
+			}
+			tokens[l.Address] = token
+		}
+
+		transfers = append(transfers, models.TokenTransfer{
+			TransactionHash:  l.TxHash.Hex(),
+			TokenAddress:     token.TokenAddress,
+			TokenName:        token.Name,
+			TokenSymbol:      token.Symbol,
+			TokenDecimals:    token.Decimals,
+			FromAddress:      strings.ToLower(common.BytesToAddress(l.Topics[1].Bytes()).Hex()),
+			ToAddress:        strings.ToLower(common.BytesToAddress(l.Topics[2].Bytes()).Hex()),
+			Value:            new(big.Int).SetBytes(l.Data).String(),
+			BlockTimestamp:   blockTime.UTC().Format(time.RFC3339),
+			BlockNumber:      strconv.FormatUint(l.BlockNumber, 10),
+			BlockHash:        l.BlockHash.Hex(),
+			TransactionIndex: int64(l.TxIndex),
+			LogIndex:         int64(l.Index),
+		})
+		lastBlock = l.BlockNumber
+	}
+
+	if cursor == "" && !reachedFromDate && end > filter.FromBlock && end > 0 {
+		cursor = strconv.FormatUint(end-1, 10)
+	}
+
+	return models.TokenTransferPage{
+		Transfers: transfers,
+		Cursor:    cursor,
+	}, nil
+}
+
+func getTokenBalance(client *ethclient.Client, token, owner common.Address, ctx context.Context) (models.TokenBalance, error) {
+	metadata, err := getTokenMetadata(client, token, ctx)
+	if err != nil {
+		return models.TokenBalance{}, err
+	}
+
+	var balance *big.Int
+	err = callContract(client, erc20ABI, token, "balanceOf", &balance, ctx, owner)
+	if err != nil {
+		return models.TokenBalance{}, err
+	}
+
+	metadata.Balance = balance.String()
+	return metadata, nil
+}
+
+func getTokenMetadata(client *ethclient.Client, token common.Address, ctx context.Context) (models.TokenBalance, error) {
+	var decimals uint8
+	err := callContract(client, erc20ABI, token, "decimals", &decimals, ctx)
+	if err != nil {
+		return models.TokenBalance{}, err
+	}
+
+	// name and symbol are optional according to the ERC-20 standard
+	var name, symbol string
+	_ = callContract(client, erc20ABI, token, "name", &name, ctx)
+	_ = callContract(client, erc20ABI, token, "symbol", &symbol, ctx)
+
+	return models.TokenBalance{
+		TokenAddress: strings.ToLower(token.Hex()),
+		Name:         name,
+		Symbol:       symbol,
+		Decimals:     int64(decimals),
+	}, nil
+}
+
+// filterTransferLogs returns ERC-20 Transfer logs sent from or to the address within the given block range
+func filterTransferLogs(client *ethclient.Client, address common.Address, fromBlock, toBlock uint64, ctx context.Context) ([]types.Log, error) {
+	addrTopic := common.BytesToHash(address.Bytes())
+	transfers := make([]types.Log, 0)
+
+	for _, topics := range [][][]common.Hash{
+		{{transferTopic}, {addrTopic}},
+		{{transferTopic}, nil, {addrTopic}},
+	} {
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(fromBlock),
+			ToBlock:   new(big.Int).SetUint64(toBlock),
+			Topics:    topics,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter transfer logs: %w", err)
+		}
+
+		for _, l := range logs {
+			// ERC-721 uses the same event signature but indexes the token id as a fourth topic
+			if len(l.Topics) != 3 || l.Removed {
+				continue
+			}
+			// Self transfers match both queries
+			if topics[1] == nil && l.Topics[1] == addrTopic {
+				continue
+			}
+			transfers = append(transfers, l)
+		}
+	}
+
+	return transfers, nil
+}
+
+func (r *RPCProvider) dial(ctx context.Context) (*ethclient.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil {
+		return r.client, nil
+	}
+
+	client, err := ethclient.DialContext(ctx, r.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial rpc: %w", err)
+	}
+	r.client = client
+
+	return client, nil
+}
+
+func callContract(client *ethclient.Client, contractABI abi.ABI, contract common.Address, method string, out any, ctx context.Context, args ...any) error {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s call: %w", method, err)
+	}
+
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+
+	return contractABI.UnpackIntoInterface(out, method, result)
+}
+
+func mapRPCTransaction(tx *types.Transaction, from common.Address, index int, block *types.Block, receipt *types.Receipt) models.Transaction {
+	to := ""
+	if tx.To() != nil {
+		to = strings.ToLower(tx.To().Hex())
+	}
+
+	contractAddress := ""
+	if receipt.ContractAddress != (common.Address{}) {
+		contractAddress = strings.ToLower(receipt.ContractAddress.Hex())
+	}
+
+	gasPrice := tx.GasPrice()
+	if receipt.EffectiveGasPrice != nil {
+		gasPrice = receipt.EffectiveGasPrice
+	}
+
+	return models.Transaction{
+		Hash:                     tx.Hash().Hex(),
+		Nonce:                    strconv.FormatUint(tx.Nonce(), 10),
+		TransactionIndex:         strconv.Itoa(index),
+		FromAddress:              strings.ToLower(from.Hex()),
+		ToAddress:                to,
+		Value:                    tx.Value().String(),
+		Gas:                      strconv.FormatUint(tx.Gas(), 10),
+		GasPrice:                 gasPrice.String(),
+		Input:                    hexutil.Encode(tx.Data()),
+		ReceiptCumulativeGasUsed: strconv.FormatUint(receipt.CumulativeGasUsed, 10),
+		ReceiptGasUsed:           strconv.FormatUint(receipt.GasUsed, 10),
+		ReceiptContractAddress:   contractAddress,
+		ReceiptRoot:              common.Bytes2Hex(receipt.PostState),
+		ReceiptStatus:            strconv.FormatUint(receipt.Status, 10),
+		BlockTimestamp:           time.Unix(int64(block.Time()), 0).UTC().Format(time.RFC3339),
+		BlockNumber:              block.Number().String(),
+		BlockHash:                block.Hash().Hex(),
+		TransferIndex:            []int64{block.Number().Int64(), int64(index)},
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	DataProviderMoralis = "moralis"
+	DataProviderRPC     = "rpc"
+)
+
+type ChainConfig struct {
+	ID            int64  `json:"id" validate:"required,gt=0"`
+	Key           string `json:"key" validate:"required,alphanum,lowercase"`
+	Name          string `json:"name" validate:"required"`
+	RPCURL        string `json:"rpc_url" validate:"required,url"`
+	NativeSymbol  string `json:"native_symbol" validate:"required"`
+	ExplorerURL   string `json:"explorer_url" validate:"omitempty,url"`
+	FaucetEnabled bool   `json:"faucet_enabled"`
+	FaucetAmount  string `json:"faucet_amount" validate:"required_if=FaucetEnabled true,omitempty,number"`
+	DataProvider  string `json:"data_provider" validate:"omitempty,oneof=moralis rpc"`
+	// WatchIncoming enables email notifications for incoming transfers. Every block is scanned, so the rpc must allow it.
+	WatchIncoming bool `json:"watch_incoming"`
+	// PriceID identifies the native coin at the price source. Testnets have none, since their coins have no value.
+	PriceID string `json:"price_id"`
+	// SignatureRPCURL overrides the rpc used to verify EIP-1271 signatures, which needs a node that can call contracts at the latest state
+	SignatureRPCURL string `json:"signature_rpc_url" validate:"omitempty,url"`
+}
+
+// HexID returns the chain id in the 0x prefixed form used by chain data providers
+func (c ChainConfig) HexID() string {
+	return fmt.Sprintf("0x%x", c.ID)
+}
+
+// SignatureRPC returns the node used to verify EIP-1271 signatures of contract wallets on the chain
+func (c ChainConfig) SignatureRPC() string {
+	if c.SignatureRPCURL != "" {
+		return c.SignatureRPCURL
+	}
+	return c.RPCURL
+}
+
+type Chains []ChainConfig
+
+// Find resolves a chain by its key, its decimal id or its 0x prefixed hex id
+func (c Chains) Find(chain string) (ChainConfig, bool) {
+	chain = strings.ToLower(chain)
+
+	var id int64 = -1
+	if strings.HasPrefix(chain, "0x") {
+		if v, err := strconv.ParseInt(chain[2:], 16, 64); err == nil {
+			id = v
+		}
+	} else if v, err := strconv.ParseInt(chain, 10, 64); err == nil {
+		id = v
+	}
+
+	for _, cfg := range c {
+		if cfg.Key == chain || cfg.ID == id {
+			return cfg, true
+		}
+	}
+
+	return ChainConfig{}, false
+}
+
+// FindByID resolves a chain by its numeric id
+func (c Chains) FindByID(id int64) (ChainConfig, bool) {
+	for _, cfg := range c {
+		if cfg.ID == id {
+			return cfg, true
+		}
+	}
+
+	return ChainConfig{}, false
+}
+
+// DefaultChains is used if no chains file is configured
+var DefaultChains = Chains{
+	{
+		ID:           1,
+		Key:          "eth",
+		Name:         "Ethereum",
+		RPCURL:       "https://cloudflare-eth.com",
+		NativeSymbol: "ETH",
+		ExplorerURL:  "https://etherscan.io",
+		DataProvider: DataProviderMoralis,
+		PriceID:      "ethereum",
+	},
+	{
+		ID:           137,
+		Key:          "polygon",
+		Name:         "Polygon",
+		RPCURL:       "https://polygon-rpc.com",
+		NativeSymbol: "MATIC",
+		ExplorerURL:  "https://polygonscan.com",
+		DataProvider: DataProviderMoralis,
+		PriceID:      "matic-network",
+	},
+	{
+		ID:            80001,
+		Key:           "mumbai",
+		Name:          "Polygon Mumbai",
+		RPCURL:        "https://rpc-mumbai.maticvigil.com/",
+		NativeSymbol:  "MATIC",
+		ExplorerURL:   "https://mumbai.polygonscan.com",
+		FaucetEnabled: true,
+		FaucetAmount:  "10000000000000000",
+		DataProvider:  DataProviderMoralis,
+		WatchIncoming: true,
+	},
+}
+
+// LoadChains reads the chain registry from a JSON file containing an array of chains
+func LoadChains(path string) (Chains, error) {
+	if path == "" {
+		return DefaultChains, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chains file: %w", err)
+	}
+
+	var chains Chains
+	if err := json.Unmarshal(content, &chains); err != nil {
+		return nil, fmt.Errorf("failed to decode chains file: %w", err)
+	}
+
+	seen := make(map[int64]struct{}, len(chains))
+	for _, chain := range chains {
+		if _, ok := seen[chain.ID]; ok {
+			return nil, fmt.Errorf("chain %d is configured twice", chain.ID)
+		}
+		seen[chain.ID] = struct{}{}
+	}
+
+	return chains, nil
+}
@@ -1,15 +1,25 @@
 package config
 
+import "time"
+
 type Config struct {
 	MoralisApiKey      string `env:"MORALIS_API_KEY" validate:"required"`
+	ChainsFile         string `env:"CHAINS_FILE"`
+	AddressAccess      string `env:"ADDRESS_ACCESS" validate:"omitempty,oneof=owned owned_and_contacts any"`
+	ENSRPCURL          string `env:"ENS_RPC_URL" validate:"omitempty,url"`
+	AdminEmails        string `env:"ADMIN_EMAILS"`
 	DBConnectionString string `env:"DB_CONNECTION_STRING" validate:"required"`
 	BackendHost        string `env:"BACKEND_HOST" validate:"required_if=UseInsecureHTTP false"`
 	FrontendURL        string `env:"FRONTEND_URL" validate:"required"`
 	DeployerURL        string `env:"DEPLOYER_URL" validate:"required"`
 	UseInsecureHTTP    bool   `env:"USE_INSECURE_HTTP"`
 	Environment        string `env:"ENVIRONMENT"`
+	Chains             Chains `env:"-" validate:"required,dive"`
 	Email              EmailConfig
 	Wallet             WalletConfig
+	Faucet             FaucetConfig
+	Prices             PriceConfig
+	ChainDataCache     ChainDataCacheConfig
 }
 
 type EmailConfig struct {
@@ -19,7 +29,75 @@ type EmailConfig struct {
 	SmtpHost string `env:"EMAIL_SMTP_HOST" validate:"required"`
 }
 
+type FaucetConfig struct {
+	DailyLimit int64 `env:"FAUCET_DAILY_LIMIT" validate:"gte=0"`
+}
+
+type PriceConfig struct {
+	Source        string `env:"PRICE_SOURCE" validate:"omitempty,oneof=coingecko static"`
+	APIURL        string `env:"PRICE_API_URL" validate:"omitempty,url"`
+	APIKey        string `env:"PRICE_API_KEY"`
+	File          string `env:"PRICE_FILE" validate:"required_if=Source static"`
+	MaxAgeSeconds int64  `env:"PRICE_MAX_AGE_SECONDS" validate:"gte=0"`
+}
+
+const (
+	CacheStoreMemory = "memory"
+	CacheStoreNone   = "none"
+
+	EndpointBalance        = "balance"
+	EndpointTransactions   = "transactions"
+	EndpointTokenBalances  = "tokens"
+	EndpointTokenTransfers = "token-transfers"
+	EndpointNFTs           = "nfts"
+)
+
+// ChainDataCacheConfig configures the cache of chain data lookups. Unset ttls use the defaults of ChainDataCacheConfig.TTL.
+type ChainDataCacheConfig struct {
+	Store                    string `env:"CHAIN_DATA_CACHE" validate:"omitempty,oneof=memory none"`
+	Size                     int    `env:"CHAIN_DATA_CACHE_SIZE" validate:"gte=0"`
+	BalanceTTLSeconds        int64  `env:"CHAIN_DATA_CACHE_BALANCE_TTL_SECONDS" validate:"gte=0"`
+	TransactionsTTLSeconds   int64  `env:"CHAIN_DATA_CACHE_TRANSACTIONS_TTL_SECONDS" validate:"gte=0"`
+	TokenBalancesTTLSeconds  int64  `env:"CHAIN_DATA_CACHE_TOKENS_TTL_SECONDS" validate:"gte=0"`
+	TokenTransfersTTLSeconds int64  `env:"CHAIN_DATA_CACHE_TOKEN_TRANSFERS_TTL_SECONDS" validate:"gte=0"`
+	NFTsTTLSeconds           int64  `env:"CHAIN_DATA_CACHE_NFTS_TTL_SECONDS" validate:"gte=0"`
+}
+
+// Enabled reports whether lookups are cached. The in-memory store is used by default.
+func (c ChainDataCacheConfig) Enabled() bool {
+	return c.Store != CacheStoreNone
+}
+
+// TTL returns how long lookups of the endpoint may be served from the cache
+func (c ChainDataCacheConfig) TTL(endpoint string) time.Duration {
+	var seconds, fallback int64
+	switch endpoint {
+	case EndpointBalance:
+		seconds, fallback = c.BalanceTTLSeconds, 15
+	case EndpointTransactions:
+		seconds, fallback = c.TransactionsTTLSeconds, 30
+	case EndpointTokenBalances:
+		seconds, fallback = c.TokenBalancesTTLSeconds, 30
+	case EndpointTokenTransfers:
+		seconds, fallback = c.TokenTransfersTTLSeconds, 30
+	case EndpointNFTs:
+		// Holdings of nfts rarely change and their metadata is expensive to look up
+		seconds, fallback = c.NFTsTTLSeconds, 300
+	}
+
+	if seconds == 0 {
+		seconds = fallback
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
 type WalletConfig struct {
-	PrivateKeyHex string `env:"WALLET_PRIVATE_KEY_HEX" validate:"required"`
-	Address       string `env:"WALLET_ADDRESS" validate:"required"`
+	// Signer must be set explicitly, so that a raw private key is never used by accident
+	Signer          string `env:"WALLET_SIGNER" validate:"required,oneof=raw keystore remote"`
+	PrivateKeyHex   string `env:"WALLET_PRIVATE_KEY_HEX" validate:"required_if=Signer raw"`
+	KeystoreFile    string `env:"WALLET_KEYSTORE_FILE" validate:"required_if=Signer keystore"`
+	PassphraseFile  string `env:"WALLET_PASSPHRASE_FILE" validate:"required_if=Signer keystore"`
+	RemoteSignerURL string `env:"WALLET_REMOTE_SIGNER_URL" validate:"required_if=Signer remote,omitempty,url"`
+	Address         string `env:"WALLET_ADDRESS" validate:"required"`
 }
@@ -0,0 +1,226 @@
+package ens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/ethutil"
+	"github.com/Leantar/elonwallet-backend/txmanager"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// See https://docs.ens.domains/contract-api-reference/name-processing for name hashing
+
+const (
+	maxNameLength   = 255
+	cacheSize       = 10000
+	cacheTTL        = 10 * time.Minute
+	missingCacheTTL = time.Minute
+	lookupTimeout   = 5 * time.Second
+	ensABIJSON      = `[
+		{"constant":true,"inputs":[{"name":"node","type":"bytes32"}],"name":"resolver","outputs":[{"name":"","type":"address"}],"type":"function"},
+		{"constant":true,"inputs":[{"name":"node","type":"bytes32"}],"name":"addr","outputs":[{"name":"","type":"address"}],"type":"function"},
+		{"constant":true,"inputs":[{"name":"node","type":"bytes32"}],"name":"name","outputs":[{"name":"","type":"string"}],"type":"function"}
+	]`
+)
+
+var (
+	ErrNotFound = errors.New("name not found")
+	ErrDisabled = errors.New("name resolution is not configured")
+
+	errEmptyResult = errors.New("call returned no data")
+
+	// Only plain ASCII names are accepted, since full ENSIP-15 normalization is not implemented
+	nameRegex       = regexp.MustCompile(`^([a-z0-9_-]+\.)+[a-z0-9-]{2,}$`)
+	registryAddress = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	ensABI          = ethutil.MustParseABI(ensABIJSON)
+)
+
+type cacheEntry struct {
+	value   string
+	err     error
+	expires time.Time
+}
+
+// Resolver resolves ENS names through an rpc node of Ethereum mainnet
+type Resolver struct {
+	url     string
+	clients *txmanager.ClientPool
+	cache   lru.BasicLRU[string, cacheEntry]
+	mu      sync.Mutex
+}
+
+func NewResolver(url string, clients *txmanager.ClientPool) *Resolver {
+	return &Resolver{
+		url:     url,
+		clients: clients,
+		cache:   lru.NewBasicLRU[string, cacheEntry](cacheSize),
+	}
+}
+
+// IsName reports whether the value looks like an ENS name rather than an address
+func IsName(value string) bool {
+	return len(value) <= maxNameLength && nameRegex.MatchString(strings.ToLower(value))
+}
+
+// NameHash implements the namehash algorithm of EIP-137
+func NameHash(name string) common.Hash {
+	node := common.Hash{}
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		labelHash := crypto.Keccak256([]byte(labels[i]))
+		node = crypto.Keccak256Hash(node.Bytes(), labelHash)
+	}
+
+	return node
+}
+
+// Resolve returns the address the name points to
+func (r *Resolver) Resolve(name string, ctx context.Context) (common.Address, error) {
+	name = strings.ToLower(name)
+	if !IsName(name) {
+		return common.Address{}, ErrNotFound
+	}
+
+	value, err := r.cached("name:"+name, func() (string, error) {
+		address, err := r.resolve(name, ctx)
+		return address.Hex(), err
+	})
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return common.HexToAddress(value), nil
+}
+
+// Reverse returns the primary name of the address. The name must resolve back to the address.
+func (r *Resolver) Reverse(address common.Address, ctx context.Context) (string, error) {
+	return r.cached("address:"+address.Hex(), func() (string, error) {
+		reverseName := strings.ToLower(strings.TrimPrefix(address.Hex(), "0x")) + ".addr.reverse"
+
+		var name string
+		err := r.call(reverseName, "name", &name, ctx)
+		if err != nil {
+			return "", err
+		}
+		if name == "" || !IsName(name) {
+			return "", ErrNotFound
+		}
+
+		// Anybody can claim any name in their reverse record, so the forward record decides
+		resolved, err := r.resolve(strings.ToLower(name), ctx)
+		if err != nil {
+			return "", err
+		}
+		if resolved != address {
+			return "", ErrNotFound
+		}
+
+		return name, nil
+	})
+}
+
+func (r *Resolver) resolve(name string, ctx context.Context) (common.Address, error) {
+	var address common.Address
+	err := r.call(name, "addr", &address, ctx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if address == (common.Address{}) {
+		return common.Address{}, ErrNotFound
+	}
+
+	return address, nil
+}
+
+// call looks up the resolver of the name and calls the method on it
+func (r *Resolver) call(name, method string, out interface{}, ctx context.Context) error {
+	if r.url == "" {
+		return ErrDisabled
+	}
+
+	client, err := r.clients.GetByURL(r.url, ctx)
+	if err != nil {
+		return err
+	}
+
+	node := NameHash(name)
+
+	var resolver common.Address
+	err = callContract(client, registryAddress, "resolver", &resolver, node, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get resolver: %w", err)
+	}
+	if resolver == (common.Address{}) {
+		return ErrNotFound
+	}
+
+	err = callContract(client, resolver, method, out, node, ctx)
+	if ethutil.IsRevert(err) || errors.Is(err, errEmptyResult) {
+		// Resolvers that do not implement the method revert or return nothing
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+
+	return nil
+}
+
+// cached memoizes lookups, including names that do not exist. Only the most recently used entries are kept.
+func (r *Resolver) cached(key string, lookup func() (string, error)) (string, error) {
+	r.mu.Lock()
+	entry, ok := r.cache.Get(key)
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.value, entry.err
+	}
+
+	value, err := lookup()
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+
+	ttl := cacheTTL
+	if err != nil {
+		ttl = missingCacheTTL
+	}
+
+	r.mu.Lock()
+	r.cache.Add(key, cacheEntry{value: value, err: err, expires: time.Now().Add(ttl)})
+	r.mu.Unlock()
+
+	return value, err
+}
+
+func callContract(client *ethclient.Client, contract common.Address, method string, out interface{}, node common.Hash, ctx context.Context) error {
+	data, err := ensABI.Pack(method, node)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return errEmptyResult
+	}
+
+	return ensABI.UnpackIntoInterface(out, method, result)
+}
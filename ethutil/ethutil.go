@@ -0,0 +1,35 @@
+package ethutil
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"strings"
+)
+
+// IsRevert reports whether the node rejected a call because the contract reverted.
+// Other rpc errors, e.g. rate limits or missing state, say nothing about the call itself.
+func IsRevert(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && strings.Contains(strings.ToLower(rpcErr.Error()), "revert")
+}
+
+// MustParseABI parses a constant contract ABI definition and panics if it is invalid
+func MustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// FormatAmount converts an amount in the smallest unit to a decimal string without trailing zeros
+func FormatAmount(amount *big.Int, decimals int64) string {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(decimals), nil)
+	formatted := new(big.Rat).SetFrac(amount, unit).FloatString(int(decimals))
+	if strings.Contains(formatted, ".") {
+		formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	}
+	return formatted
+}
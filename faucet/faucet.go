@@ -0,0 +1,200 @@
+package faucet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/Leantar/elonwallet-backend/txmanager"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDailyLimit = 1
+	// A pending drip without a transaction after this duration was abandoned, e.g. because the process stopped
+	abandonedAfter = 10 * time.Minute
+)
+
+var (
+	ErrDisabled     = errors.New("faucet is disabled for this chain")
+	ErrLimitReached = errors.New("daily faucet limit reached")
+	ErrEmpty        = errors.New("faucet funding wallet has insufficient balance")
+)
+
+// Service sends test coins from the backend funding wallet
+type Service struct {
+	tf         common.TransactionFactory
+	chains     config.Chains
+	from       string
+	dailyLimit int64
+	nonces     *txmanager.NonceManager
+	clients    *txmanager.ClientPool
+}
+
+func NewService(cfg config.Config, tf common.TransactionFactory, clients *txmanager.ClientPool, nonces *txmanager.NonceManager) *Service {
+	dailyLimit := cfg.Faucet.DailyLimit
+	if dailyLimit == 0 {
+		dailyLimit = defaultDailyLimit
+	}
+
+	return &Service{
+		tf:         tf,
+		chains:     cfg.Chains,
+		from:       nonces.Address().Hex(),
+		dailyLimit: dailyLimit,
+		nonces:     nonces,
+		clients:    clients,
+	}
+}
+
+// Drip sends the configured amount of test coins to the address.
+// The drip is committed before anything is broadcast, so coins are never sent without a record that counts towards the limits.
+func (s *Service) Drip(userID, address string, chain config.ChainConfig, ctx context.Context) (models.FaucetDrip, error) {
+	drip, err := s.reserve(userID, address, chain, ctx)
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	err = s.sendDrip(&drip, chain, ctx)
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	return drip, nil
+}
+
+// reserve checks the limits and records a pending drip. The locks are only held while the limits are checked.
+func (s *Service) reserve(userID, address string, chain config.ChainConfig, ctx context.Context) (models.FaucetDrip, error) {
+	amount, err := dripAmount(chain)
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	tx, err := s.tf.Begin()
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	drip, err := s.reserveInTx(tx, userID, address, chain.ID, amount, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return models.FaucetDrip{}, rbErr
+		}
+		return models.FaucetDrip{}, err
+	}
+
+	return drip, tx.Commit()
+}
+
+func dripAmount(chain config.ChainConfig) (*big.Int, error) {
+	if !chain.FaucetEnabled {
+		return nil, ErrDisabled
+	}
+
+	amount, ok := new(big.Int).SetString(chain.FaucetAmount, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid faucet amount for chain %d: %s", chain.ID, chain.FaucetAmount)
+	}
+
+	return amount, nil
+}
+
+func (s *Service) reserveInTx(tx common.Transaction, userID, address string, chainID int64, amount *big.Int, ctx context.Context) (models.FaucetDrip, error) {
+	err := tx.Faucet().LockDripTarget(userID, address, ctx)
+	if err != nil {
+		return models.FaucetDrip{}, fmt.Errorf("failed to lock faucet: %w", err)
+	}
+
+	now := time.Now()
+	err = tx.Faucet().FailAbandonedDrips(userID, address, chainID, now.Add(-abandonedAfter).Unix(), ctx)
+	if err != nil {
+		return models.FaucetDrip{}, fmt.Errorf("failed to fail abandoned drips: %w", err)
+	}
+
+	count, err := tx.Faucet().CountDripsSince(userID, address, chainID, now.Add(-24*time.Hour).Unix(), ctx)
+	if err != nil {
+		return models.FaucetDrip{}, fmt.Errorf("failed to count drips: %w", err)
+	}
+	if count >= s.dailyLimit {
+		return models.FaucetDrip{}, ErrLimitReached
+	}
+
+	drip := models.FaucetDrip{
+		UserID:  userID,
+		Address: strings.ToLower(address),
+		ChainID: chainID,
+		Amount:  amount.String(),
+		Status:  models.FaucetDripPending,
+		Created: now.Unix(),
+	}
+
+	drip.ID, err = tx.Faucet().CreateDrip(drip, ctx)
+	if err != nil {
+		return models.FaucetDrip{}, fmt.Errorf("failed to record drip: %w", err)
+	}
+
+	return drip, nil
+}
+
+// sendDrip signs and broadcasts the transaction of a reserved drip. The drip is failed if nothing was sent.
+func (s *Service) sendDrip(drip *models.FaucetDrip, chain config.ChainConfig, ctx context.Context) error {
+	client, err := s.clients.Get(chain.ID, ctx)
+	if err != nil {
+		return s.failDrip(drip, err, ctx)
+	}
+
+	signedTx, err := s.prepare(client, drip, ctx)
+	if err != nil {
+		return s.failDrip(drip, err, ctx)
+	}
+	drip.TxHash = signedTx.Hash().Hex()
+
+	err = s.nonces.Broadcast(client, chain.ID, signedTx, ctx)
+	if errors.Is(err, txmanager.ErrRejected) {
+		return s.failDrip(drip, err, ctx)
+	}
+	if err != nil {
+		// The transaction is recorded, so the confirmer rebroadcasts it
+		log.Warn().Caller().Err(err).Str("hash", drip.TxHash).Msg("failed to broadcast drip")
+	}
+
+	drip.Status = models.FaucetDripSent
+	err = s.setDripStatus(drip.ID, drip.Status, ctx)
+	if err != nil {
+		// The confirmer settles the status once the transaction is mined
+		log.Error().Caller().Err(err).Int64("drip_id", drip.ID).Msg("failed to mark drip as sent")
+	}
+
+	return nil
+}
+
+func (s *Service) failDrip(drip *models.FaucetDrip, cause error, ctx context.Context) error {
+	drip.Status = models.FaucetDripFailed
+	err := s.setDripStatus(drip.ID, drip.Status, ctx)
+	if err != nil {
+		log.Error().Caller().Err(err).Int64("drip_id", drip.ID).Msg("failed to mark drip as failed")
+	}
+	return cause
+}
+
+func (s *Service) setDripStatus(id int64, status string, ctx context.Context) error {
+	tx, err := s.tf.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = tx.Faucet().SetDripStatus(id, status, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
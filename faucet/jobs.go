@@ -0,0 +1,240 @@
+package faucet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/rs/zerolog/log"
+	"strings"
+	"time"
+)
+
+const (
+	jobMaxAttempts = 5
+	// The delay doubles with every failed attempt
+	jobRetryDelay = time.Minute
+	// A sending job is claimed again after the lease, in case its worker stopped
+	jobLease = 5 * time.Minute
+)
+
+// Enqueue schedules a drip for the worker. The job is only persisted if tx is committed by the caller.
+func (s *Service) Enqueue(userID, address string, chain config.ChainConfig, tx common.Transaction, ctx context.Context) (models.FaucetJob, error) {
+	if !chain.FaucetEnabled {
+		return models.FaucetJob{}, ErrDisabled
+	}
+
+	now := time.Now().Unix()
+	job := models.FaucetJob{
+		UserID:   userID,
+		Address:  strings.ToLower(address),
+		ChainID:  chain.ID,
+		Status:   models.FaucetJobQueued,
+		RunAfter: now,
+		Created:  now,
+		Updated:  now,
+	}
+
+	var err error
+	job.ID, err = tx.Faucet().CreateJob(job, ctx)
+	if err != nil {
+		return models.FaucetJob{}, fmt.Errorf("failed to create faucet job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ProcessNextJob runs the oldest due job and reports whether there was one.
+// Claiming, reserving the drip and the result are committed separately, so that no transaction is open while broadcasting.
+// A job whose worker stopped is claimed again once its lease expired and reconciles the drip it already recorded.
+func (s *Service) ProcessNextJob(ctx context.Context) (bool, error) {
+	job, err := s.claimJob(ctx)
+	if errors.Is(err, common.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.runJob(&job, ctx)
+
+	err = s.updateJob(job, ctx)
+	if err != nil {
+		return true, fmt.Errorf("failed to update faucet job %d: %w", job.ID, err)
+	}
+
+	return true, nil
+}
+
+// claimJob marks the next due job as sending and counts the attempt
+func (s *Service) claimJob(ctx context.Context) (models.FaucetJob, error) {
+	tx, err := s.tf.Begin()
+	if err != nil {
+		return models.FaucetJob{}, err
+	}
+
+	job, err := tx.Faucet().ClaimNextJob(time.Now().Unix(), ctx)
+	if err == nil {
+		now := time.Now()
+		job.Status = models.FaucetJobSending
+		job.Attempts++
+		job.RunAfter = now.Add(jobLease).Unix()
+		job.Updated = now.Unix()
+		err = tx.Faucet().UpdateJob(job, ctx)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return models.FaucetJob{}, rbErr
+		}
+		return models.FaucetJob{}, err
+	}
+
+	return job, tx.Commit()
+}
+
+func (s *Service) updateJob(job models.FaucetJob, ctx context.Context) error {
+	tx, err := s.tf.Begin()
+	if err != nil {
+		return err
+	}
+
+	job.Updated = time.Now().Unix()
+	err = tx.Faucet().UpdateJob(job, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Service) runJob(job *models.FaucetJob, ctx context.Context) {
+	chain, ok := s.chains.FindByID(job.ChainID)
+	if !ok {
+		job.Status = models.FaucetJobSkipped
+		job.Error = "chain is no longer supported"
+		return
+	}
+
+	drip, err := s.jobDrip(job, chain, ctx)
+	switch {
+	case err == nil:
+		job.Status = models.FaucetJobSent
+		job.TxHash = drip.TxHash
+		job.Error = ""
+	case errors.Is(err, ErrDisabled), errors.Is(err, ErrLimitReached):
+		job.Status = models.FaucetJobSkipped
+		job.Error = jobError(err)
+	case job.Attempts >= jobMaxAttempts:
+		log.Error().Caller().Err(err).Int64("job_id", job.ID).Msg("faucet job failed")
+		job.Status = models.FaucetJobFailed
+		job.Error = jobError(err)
+	default:
+		log.Warn().Caller().Err(err).Int64("job_id", job.ID).Msg("faucet job will be retried")
+		job.Status = models.FaucetJobQueued
+		job.Error = jobError(err)
+		job.RunAfter = time.Now().Add(jobRetryDelay << (job.Attempts - 1)).Unix()
+	}
+}
+
+// jobError returns the message shown to the user. Other errors may contain node urls or database details, so they are only logged.
+func jobError(err error) string {
+	switch {
+	case errors.Is(err, ErrDisabled):
+		return ErrDisabled.Error()
+	case errors.Is(err, ErrLimitReached):
+		return ErrLimitReached.Error()
+	case errors.Is(err, ErrEmpty):
+		return "faucet is out of funds"
+	default:
+		return "failed to send drip"
+	}
+}
+
+// jobDrip sends the drip of the job. A drip recorded by an earlier attempt is only sent again if it failed.
+func (s *Service) jobDrip(job *models.FaucetJob, chain config.ChainConfig, ctx context.Context) (models.FaucetDrip, error) {
+	if job.DripID != 0 {
+		drip, err := s.reconcileDrip(job.DripID, ctx)
+		if err != nil && !errors.Is(err, common.ErrNotFound) {
+			return models.FaucetDrip{}, fmt.Errorf("failed to reconcile drip %d: %w", job.DripID, err)
+		}
+		if err == nil && drip.Status != models.FaucetDripFailed {
+			return drip, nil
+		}
+		job.DripID = 0
+	}
+
+	drip, err := s.reserveForJob(job, chain, ctx)
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	err = s.sendDrip(&drip, chain, ctx)
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	return drip, nil
+}
+
+// reconcileDrip returns the drip of an earlier attempt. A drip without a transaction was interrupted before it was signed,
+// so it is failed. The row lock makes a late signing attempt of the interrupted worker fail as well.
+func (s *Service) reconcileDrip(id int64, ctx context.Context) (models.FaucetDrip, error) {
+	tx, err := s.tf.Begin()
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	drip, err := tx.Faucet().LockDrip(id, ctx)
+	if err == nil && drip.Status == models.FaucetDripPending && drip.TxHash == "" {
+		drip.Status = models.FaucetDripFailed
+		err = tx.Faucet().SetDripStatus(drip.ID, drip.Status, ctx)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return models.FaucetDrip{}, rbErr
+		}
+		return models.FaucetDrip{}, err
+	}
+
+	return drip, tx.Commit()
+}
+
+// reserveForJob reserves a drip and links it to the job in the same transaction
+func (s *Service) reserveForJob(job *models.FaucetJob, chain config.ChainConfig, ctx context.Context) (models.FaucetDrip, error) {
+	amount, err := dripAmount(chain)
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	tx, err := s.tf.Begin()
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+
+	drip, err := s.reserveInTx(tx, job.UserID, job.Address, chain.ID, amount, ctx)
+	if err == nil {
+		linked := *job
+		linked.DripID = drip.ID
+		linked.Updated = time.Now().Unix()
+		err = tx.Faucet().UpdateJob(linked, ctx)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return models.FaucetDrip{}, rbErr
+		}
+		return models.FaucetDrip{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return models.FaucetDrip{}, err
+	}
+	job.DripID = drip.ID
+
+	return drip, nil
+}
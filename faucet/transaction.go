@@ -0,0 +1,107 @@
+package faucet
+
+import (
+	"context"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+)
+
+// prepare signs the transfer of the drip and records it together with the drip's transaction hash
+func (s *Service) prepare(client *ethclient.Client, drip *models.FaucetDrip, ctx context.Context) (*types.Transaction, error) {
+	amount, _ := new(big.Int).SetString(drip.Amount, 10)
+
+	balance, err := client.BalanceAt(ctx, ethcommon.HexToAddress(s.from), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get funding wallet balance: %w", err)
+	}
+	if balance.Cmp(amount) < 0 {
+		return nil, ErrEmpty
+	}
+
+	tx, err := s.tf.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	signedTx, err := s.prepareInTx(tx, client, drip, amount, balance, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, rbErr
+		}
+		return nil, err
+	}
+
+	return signedTx, tx.Commit()
+}
+
+func (s *Service) prepareInTx(tx common.Transaction, client *ethclient.Client, drip *models.FaucetDrip, amount, balance *big.Int, ctx context.Context) (*types.Transaction, error) {
+	// The nonce is allocated by the nonce manager, so concurrent drips never reuse it
+	signedTx, err := s.nonces.Prepare(tx, client, drip.ChainID, func(nonce uint64) (*types.Transaction, error) {
+		unsignedTx, err := s.createTransaction(client, drip.Address, amount, drip.ChainID, nonce, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tx: %w", err)
+		}
+
+		// Cost includes the maximum gas fee
+		if balance.Cmp(unsignedTx.Cost()) < 0 {
+			return nil, ErrEmpty
+		}
+
+		return unsignedTx, nil
+	}, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fails if the drip was given up in the meantime, which rolls back the nonce and the recorded transaction
+	err = tx.Faucet().SetDripTransaction(drip.ID, signedTx.Hash().Hex(), ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach transaction to drip %d: %w", drip.ID, err)
+	}
+
+	return signedTx, nil
+}
+
+func (s *Service) createTransaction(client *ethclient.Client, to string, amount *big.Int, chainID int64, nonce uint64, ctx context.Context) (*types.Transaction, error) {
+	sender := ethcommon.HexToAddress(s.from)
+	receiver := ethcommon.HexToAddress(to)
+
+	feeCap, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest fee cap: %w", err)
+	}
+
+	tipCap, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest tipCap cap: %w", err)
+	}
+
+	// Contract wallets may need more than the 21000 gas of a plain transfer
+	gas, err := client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  sender,
+		To:    &receiver,
+		Value: amount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+
+	dynTx := &types.DynamicFeeTx{
+		ChainID:   new(big.Int).SetInt64(chainID),
+		Nonce:     nonce,
+		GasFeeCap: feeCap,
+		GasTipCap: tipCap,
+		Gas:       gas,
+		To:        &receiver,
+		Value:     amount,
+		Data:      make([]byte, 0),
+	}
+
+	return types.NewTx(dynTx), nil
+}
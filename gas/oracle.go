@@ -0,0 +1,148 @@
+package gas
+
+import (
+	"context"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/txmanager"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	feeCacheTTL      = 5 * time.Second
+	feeHistoryBlocks = 20
+)
+
+// Reward percentiles of the slow, normal and fast tier
+var feePercentiles = []float64{10, 50, 90}
+
+type cachedFees struct {
+	fees    models.FeeSuggestion
+	expires time.Time
+}
+
+// Oracle suggests fees based on eth_feeHistory and estimates gas
+type Oracle struct {
+	clients *txmanager.ClientPool
+	cache   map[int64]cachedFees
+	mu      sync.Mutex
+}
+
+func NewOracle(clients *txmanager.ClientPool) *Oracle {
+	return &Oracle{
+		clients: clients,
+		cache:   make(map[int64]cachedFees),
+	}
+}
+
+// Fees returns the fee suggestion for the chain. Suggestions are cached for a few seconds per chain.
+func (o *Oracle) Fees(chainID int64, ctx context.Context) (models.FeeSuggestion, error) {
+	o.mu.Lock()
+	cached, ok := o.cache[chainID]
+	o.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.fees, nil
+	}
+
+	client, err := o.clients.Get(chainID, ctx)
+	if err != nil {
+		return models.FeeSuggestion{}, err
+	}
+
+	fees, err := suggestFees(client, ctx)
+	if err != nil {
+		return models.FeeSuggestion{}, err
+	}
+	fees.ChainID = chainID
+
+	o.mu.Lock()
+	o.cache[chainID] = cachedFees{fees: fees, expires: time.Now().Add(feeCacheTTL)}
+	o.mu.Unlock()
+
+	return fees, nil
+}
+
+// EstimateGas returns the gas needed by the call
+func (o *Oracle) EstimateGas(chainID int64, msg ethereum.CallMsg, ctx context.Context) (uint64, error) {
+	client, err := o.clients.Get(chainID, ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return client.EstimateGas(ctx, msg)
+}
+
+func suggestFees(client *ethclient.Client, ctx context.Context) (models.FeeSuggestion, error) {
+	history, err := client.FeeHistory(ctx, feeHistoryBlocks, nil, feePercentiles)
+	if err != nil {
+		return models.FeeSuggestion{}, fmt.Errorf("failed to get fee history: %w", err)
+	}
+	if len(history.BaseFee) == 0 {
+		return models.FeeSuggestion{}, fmt.Errorf("fee history is empty")
+	}
+
+	suggestedTip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return models.FeeSuggestion{}, fmt.Errorf("failed to suggest tip cap: %w", err)
+	}
+
+	// The last base fee is the one of the next block
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+	tiers := make([]models.FeeTier, len(feePercentiles))
+	for i := range feePercentiles {
+		tip := medianReward(history.Reward, i)
+		if tip == nil {
+			tip = suggestedTip
+		}
+
+		// Same headroom as geth uses, so the transaction stays valid through several full blocks
+		maxFee := new(big.Int).Mul(baseFee, big.NewInt(2))
+		maxFee.Add(maxFee, tip)
+
+		tiers[i] = models.FeeTier{
+			MaxPriorityFeePerGas: tip.String(),
+			MaxFeePerGas:         maxFee.String(),
+		}
+	}
+
+	blockNumber := uint64(0)
+	if history.OldestBlock != nil {
+		blockNumber = history.OldestBlock.Uint64() + uint64(len(history.Reward))
+		if blockNumber > 0 {
+			blockNumber--
+		}
+	}
+
+	return models.FeeSuggestion{
+		BaseFee:      baseFee.String(),
+		SuggestedTip: suggestedTip.String(),
+		Slow:         tiers[0],
+		Normal:       tiers[1],
+		Fast:         tiers[2],
+		BlockNumber:  blockNumber,
+	}, nil
+}
+
+// medianReward returns the median of the rewards at the percentile index, ignoring empty blocks which report zero rewards
+func medianReward(rewards [][]*big.Int, index int) *big.Int {
+	values := make([]*big.Int, 0, len(rewards))
+	for _, blockRewards := range rewards {
+		if index < len(blockRewards) && blockRewards[index] != nil && blockRewards[index].Sign() > 0 {
+			values = append(values, blockRewards[index])
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Cmp(values[j]) < 0
+	})
+
+	return values[len(values)/2]
+}
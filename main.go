@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/Leantar/elonwallet-backend/chaindata"
 	"github.com/Leantar/elonwallet-backend/config"
 	"github.com/Leantar/elonwallet-backend/repository"
 	"github.com/Leantar/elonwallet-backend/server"
@@ -10,18 +13,84 @@ import (
 	"github.com/rs/zerolog/log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 )
 
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	zerolog.SetGlobalLevel(zerolog.DebugLevel)
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal().Caller().Err(err).Msg("failed to migrate")
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		log.Fatal().Caller().Err(err).Msg("failed to start")
 	}
 }
 
+// runMigrate handles "migrate status", "migrate up" and "migrate down [steps]"
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate status|up|down [steps]")
+	}
+
+	var cfg config.Config
+	err := config.FromEnv(&cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.DBConnectionString == "" {
+		return errors.New("DB_CONNECTION_STRING is required")
+	}
+
+	migrator, err := repository.NewMigrator(cfg.DBConnectionString)
+	if err != nil {
+		return fmt.Errorf("failed to create Migrator: %w", err)
+	}
+	defer func() {
+		_ = migrator.Close()
+	}()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.Baseline {
+				state = "baseline, present but not recorded"
+			}
+			if s.Applied {
+				state = fmt.Sprintf("applied at %s", time.Unix(s.AppliedAt, 0).UTC().Format(time.RFC3339))
+			}
+			fmt.Printf("%04d %-30s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		return migrator.Down(steps, ctx)
+	default:
+		return fmt.Errorf("unknown migrate command: %s", args[0])
+	}
+}
+
 func run() error {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM)
@@ -32,6 +101,11 @@ func run() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	cfg.Chains, err = config.LoadChains(cfg.ChainsFile)
+	if err != nil {
+		return fmt.Errorf("failed to load chains: %w", err)
+	}
+
 	err = validator.New().Struct(cfg)
 	if err != nil {
 		return fmt.Errorf("validation of config failed: %w", err)
@@ -42,7 +116,7 @@ func run() error {
 		return fmt.Errorf("failed to create TransactionFactory: %w", err)
 	}
 
-	s, err := server.New(cfg, tf)
+	s, err := server.New(cfg, tf, chaindata.WithCache(chaindata.NewRouter(cfg), cfg.ChainDataCache))
 	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
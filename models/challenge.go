@@ -0,0 +1,8 @@
+package models
+
+type Challenge struct {
+	Address   string `json:"address"`
+	UserID    string `json:"user_id"`
+	Challenge string `json:"challenge"`
+	ExpiresAt int64  `json:"expires_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+const (
+	FaucetJobQueued  = "queued"
+	FaucetJobSending = "sending"
+	FaucetJobSent    = "sent"
+	FaucetJobSkipped = "skipped"
+	FaucetJobFailed  = "failed"
+
+	FaucetDripPending   = "pending"
+	FaucetDripSent      = "sent"
+	FaucetDripConfirmed = "confirmed"
+	FaucetDripFailed    = "failed"
+)
+
+type FaucetDrip struct {
+	ID      int64  `json:"id"`
+	UserID  string `json:"user_id"`
+	Address string `json:"address"`
+	ChainID int64  `json:"chain_id"`
+	Amount  string `json:"amount"`
+	TxHash  string `json:"tx_hash"`
+	Status  string `json:"status"`
+	Created int64  `json:"created"`
+}
+
+// FaucetJob is a drip that is processed in the background
+type FaucetJob struct {
+	ID       int64  `json:"id"`
+	UserID   string `json:"-"`
+	Address  string `json:"address"`
+	ChainID  int64  `json:"chain_id"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	TxHash   string `json:"tx_hash,omitempty"`
+	DripID   int64  `json:"-"`
+	Attempts int64  `json:"attempts"`
+	RunAfter int64  `json:"-"`
+	Created  int64  `json:"created"`
+	Updated  int64  `json:"updated"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// FeeTier contains EIP-1559 fee caps in wei
+type FeeTier struct {
+	MaxPriorityFeePerGas string `json:"max_priority_fee_per_gas"`
+	MaxFeePerGas         string `json:"max_fee_per_gas"`
+}
+
+type FeeSuggestion struct {
+	ChainID      int64   `json:"chain_id"`
+	BaseFee      string  `json:"base_fee"`
+	SuggestedTip string  `json:"suggested_tip"`
+	Slow         FeeTier `json:"slow"`
+	Normal       FeeTier `json:"normal"`
+	Fast         FeeTier `json:"fast"`
+	BlockNumber  uint64  `json:"block_number"`
+}
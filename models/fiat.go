@@ -0,0 +1,8 @@
+package models
+
+type FiatValue struct {
+	Currency  string `json:"currency"`
+	Value     string `json:"value"`
+	Price     string `json:"price,omitempty"`
+	UpdatedAt int64  `json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+type NFT struct {
+	TokenAddress     string `json:"token_address"`
+	TokenID          string `json:"token_id"`
+	Amount           string `json:"amount"`
+	ContractType     string `json:"contract_type"`
+	CollectionName   string `json:"collection_name"`
+	CollectionSymbol string `json:"collection_symbol"`
+	TokenURI         string `json:"token_uri"`
+	Name             string `json:"name"`
+	Image            string `json:"image"`
+}
+
+type NFTPage struct {
+	NFTs   []NFT  `json:"nfts"`
+	Cursor string `json:"cursor"`
+}
@@ -0,0 +1,27 @@
+package models
+
+const (
+	OutboundStatusPending   = "pending"
+	OutboundStatusConfirmed = "confirmed"
+	OutboundStatusFailed    = "failed"
+	OutboundStatusReplaced  = "replaced"
+	OutboundStatusDropped   = "dropped"
+)
+
+// OutboundTransaction is a transaction sent by the backend funding wallet or relayed for a user
+type OutboundTransaction struct {
+	ID          int64  `json:"id"`
+	UserID      string `json:"user_id,omitempty"`
+	ChainID     int64  `json:"chain_id"`
+	Hash        string `json:"hash"`
+	Nonce       uint64 `json:"nonce"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Value       string `json:"value"`
+	RawTx       string `json:"-"`
+	Status      string `json:"status"`
+	BlockNumber uint64 `json:"block_number"`
+	Attempts    int64  `json:"attempts"`
+	Created     int64  `json:"created"`
+	Updated     int64  `json:"updated"`
+}
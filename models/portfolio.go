@@ -0,0 +1,32 @@
+package models
+
+// PortfolioChain contains the holdings of one wallet on one chain
+type PortfolioChain struct {
+	ChainID int64          `json:"chain_id"`
+	Balance string         `json:"balance"`
+	Tokens  []TokenBalance `json:"tokens"`
+	Error   string         `json:"error,omitempty"`
+}
+
+type PortfolioWallet struct {
+	Name    string           `json:"name"`
+	Address string           `json:"address"`
+	Chains  []PortfolioChain `json:"chains"`
+}
+
+// PortfolioTotal sums the holdings of all wallets on one chain
+type PortfolioTotal struct {
+	ChainID int64          `json:"chain_id"`
+	Balance string         `json:"balance"`
+	Tokens  []TokenBalance `json:"tokens"`
+	Fiat    *FiatValue     `json:"fiat,omitempty"`
+}
+
+type Portfolio struct {
+	Wallets []PortfolioWallet `json:"wallets"`
+	Totals  []PortfolioTotal  `json:"totals"`
+	// FiatTotal is the value of the native coins on all chains with a known price
+	FiatTotal *FiatValue `json:"fiat_total,omitempty"`
+	// Partial is set if the holdings of at least one wallet could not be loaded
+	Partial bool `json:"partial"`
+}
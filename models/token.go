@@ -0,0 +1,32 @@
+package models
+
+type TokenBalance struct {
+	TokenAddress string `json:"token_address"`
+	Name         string `json:"name"`
+	Symbol       string `json:"symbol"`
+	Logo         string `json:"logo"`
+	Decimals     int64  `json:"decimals"`
+	Balance      string `json:"balance"`
+}
+
+type TokenTransfer struct {
+	TransactionHash  string `json:"transaction_hash"`
+	TokenAddress     string `json:"token_address"`
+	TokenName        string `json:"token_name"`
+	TokenSymbol      string `json:"token_symbol"`
+	TokenLogo        string `json:"token_logo"`
+	TokenDecimals    int64  `json:"token_decimals"`
+	FromAddress      string `json:"from_address"`
+	ToAddress        string `json:"to_address"`
+	Value            string `json:"value"`
+	BlockTimestamp   string `json:"block_timestamp"`
+	BlockNumber      string `json:"block_number"`
+	BlockHash        string `json:"block_hash"`
+	TransactionIndex int64  `json:"transaction_index"`
+	LogIndex         int64  `json:"log_index"`
+}
+
+type TokenTransferPage struct {
+	Transfers []TokenTransfer `json:"transfers"`
+	Cursor    string          `json:"cursor"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	DirectionIn   = "in"
+	DirectionOut  = "out"
+	DirectionSelf = "self"
+)
+
+type Transaction struct {
+	Hash                     string  `json:"hash"`
+	Nonce                    string  `json:"nonce"`
+	TransactionIndex         string  `json:"transaction_index"`
+	FromAddress              string  `json:"from_address"`
+	ToAddress                string  `json:"to_address"`
+	Value                    string  `json:"value"`
+	Gas                      string  `json:"gas"`
+	GasPrice                 string  `json:"gas_price"`
+	Input                    string  `json:"input"`
+	ReceiptCumulativeGasUsed string  `json:"receipt_cumulative_gas_used"`
+	ReceiptGasUsed           string  `json:"receipt_gas_used"`
+	ReceiptContractAddress   string  `json:"receipt_contract_address"`
+	ReceiptRoot              string  `json:"receipt_root"`
+	ReceiptStatus            string  `json:"receipt_status"`
+	BlockTimestamp           string  `json:"block_timestamp"`
+	BlockNumber              string  `json:"block_number"`
+	BlockHash                string  `json:"block_hash"`
+	TransferIndex            []int64 `json:"transfer_index"`
+	FromLabel                string  `json:"from_label,omitempty"`
+	ToLabel                  string  `json:"to_label,omitempty"`
+	Direction                string  `json:"direction,omitempty"`
+}
+
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	Total        int64         `json:"total"`
+	Cursor       string        `json:"cursor"`
+}
+
+// PageFilter narrows down a paginated history lookup. Zero values mean "no restriction".
+type PageFilter struct {
+	Cursor    string
+	Limit     int64
+	FromBlock uint64
+	ToBlock   uint64
+	FromDate  time.Time
+	ToDate    time.Time
+}
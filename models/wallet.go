@@ -1,6 +1,7 @@
 package models
 
 type Wallet struct {
-	Name    string `json:"name"`
-	Address string `json:"address"`
+	Name    string  `json:"name"`
+	Address string  `json:"address"`
+	Chains  []int64 `json:"chains"`
 }
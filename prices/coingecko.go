@@ -0,0 +1,112 @@
+package prices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCoinGeckoURL = "https://api.coingecko.com/api/v3"
+)
+
+// CoinGeckoSource uses the simple price api of CoinGecko or a compatible service
+type CoinGeckoSource struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func NewCoinGeckoSource(apiURL, apiKey string) *CoinGeckoSource {
+	if apiURL == "" {
+		apiURL = defaultCoinGeckoURL
+	}
+
+	return &CoinGeckoSource{
+		url:    strings.TrimSuffix(apiURL, "/"),
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (cg *CoinGeckoSource) GetQuote(assetID, currency string, ctx context.Context) (Quote, error) {
+	query := url.Values{}
+	query.Set("ids", assetID)
+	query.Set("vs_currencies", currency)
+	query.Set("include_last_updated_at", "true")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cg.url+"/simple/price?"+query.Encode(), nil)
+	if err != nil {
+		return Quote{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if cg.apiKey != "" {
+		req.Header.Set("x-cg-demo-api-key", cg.apiKey)
+	}
+
+	resp, err := cg.client.Do(req)
+	if err != nil {
+		return Quote{}, fmt.Errorf("failed to get price: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("price source returned status %d", resp.StatusCode)
+	}
+
+	prices, err := decodePrices(resp.Body)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	return findQuote(prices, assetID, currency, time.Now())
+}
+
+// priceTable maps asset ids to currency prices, optionally with a last_updated_at unix time
+type priceTable map[string]map[string]json.Number
+
+func decodePrices(body io.Reader) (priceTable, error) {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+
+	var prices priceTable
+	if err := decoder.Decode(&prices); err != nil {
+		return nil, fmt.Errorf("failed to decode prices: %w", err)
+	}
+
+	return prices, nil
+}
+
+func findQuote(prices priceTable, assetID, currency string, fallbackUpdatedAt time.Time) (Quote, error) {
+	asset, ok := prices[assetID]
+	if !ok {
+		return Quote{}, ErrUnknownAsset
+	}
+
+	price, ok := asset[currency]
+	if !ok {
+		return Quote{}, ErrUnknownAsset
+	}
+
+	rat, ok := new(big.Rat).SetString(price.String())
+	if !ok {
+		return Quote{}, fmt.Errorf("invalid price %s", price)
+	}
+
+	updatedAt := fallbackUpdatedAt
+	if lastUpdated, ok := asset["last_updated_at"]; ok {
+		if unix, err := lastUpdated.Int64(); err == nil {
+			updatedAt = time.Unix(unix, 0)
+		}
+	}
+
+	return Quote{Price: rat, UpdatedAt: updatedAt}, nil
+}
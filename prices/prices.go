@@ -0,0 +1,131 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	SourceCoinGecko = "coingecko"
+	SourceStatic    = "static"
+
+	defaultMaxAge = 10 * time.Minute
+	// Prices are refreshed at most once per interval, older cached prices are still served if the source fails
+	refreshInterval = time.Minute
+)
+
+var (
+	ErrUnknownAsset = errors.New("no price for asset")
+	ErrStale        = errors.New("price is stale")
+)
+
+// Quote is the price of one unit of an asset
+type Quote struct {
+	Price     *big.Rat
+	UpdatedAt time.Time
+}
+
+// Source provides prices of assets in a fiat currency
+type Source interface {
+	GetQuote(assetID, currency string, ctx context.Context) (Quote, error)
+}
+
+type cachedQuote struct {
+	quote   Quote
+	fetched time.Time
+	// err is set if the last fetch failed without an older quote to fall back to
+	err error
+}
+
+// Service caches the quotes of a source and refuses to return quotes older than the staleness limit
+type Service struct {
+	source Source
+	maxAge time.Duration
+	cache  map[string]cachedQuote
+	mu     sync.Mutex
+}
+
+// NewSource creates the price source selected by the config
+func NewSource(cfg config.PriceConfig) (Source, error) {
+	switch cfg.Source {
+	case SourceStatic:
+		return NewStaticSource(cfg.File)
+	case SourceCoinGecko, "":
+		return NewCoinGeckoSource(cfg.APIURL, cfg.APIKey), nil
+	default:
+		return nil, fmt.Errorf("unknown price source %s", cfg.Source)
+	}
+}
+
+func NewService(source Source, maxAge time.Duration) *Service {
+	if maxAge <= 0 {
+		maxAge = defaultMaxAge
+	}
+
+	return &Service{
+		source: source,
+		maxAge: maxAge,
+		cache:  make(map[string]cachedQuote),
+	}
+}
+
+func (s *Service) GetQuote(assetID, currency string, ctx context.Context) (Quote, error) {
+	key := assetID + ":" + currency
+	now := time.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.fetched) < refreshInterval {
+		if cached.err != nil {
+			return Quote{}, cached.err
+		}
+		return s.checkAge(cached.quote, now)
+	}
+
+	quote, err := s.source.GetQuote(assetID, currency, ctx)
+	if err != nil && ctx.Err() != nil {
+		return Quote{}, err
+	}
+	if err != nil {
+		// Failures are remembered as well, so that a failing source is only asked once per interval
+		failed := cachedQuote{fetched: now, err: err}
+		if ok && cached.err == nil && !errors.Is(err, ErrUnknownAsset) {
+			failed = cachedQuote{quote: cached.quote, fetched: now}
+		}
+
+		s.mu.Lock()
+		s.cache[key] = failed
+		s.mu.Unlock()
+
+		if failed.err != nil {
+			return Quote{}, err
+		}
+		return s.checkAge(failed.quote, now)
+	}
+
+	s.mu.Lock()
+	s.cache[key] = cachedQuote{quote: quote, fetched: now}
+	s.mu.Unlock()
+
+	return s.checkAge(quote, now)
+}
+
+func (s *Service) checkAge(quote Quote, now time.Time) (Quote, error) {
+	if now.Sub(quote.UpdatedAt) > s.maxAge {
+		return Quote{}, ErrStale
+	}
+	return quote, nil
+}
+
+// Value converts an amount in the smallest unit of an asset with the given decimals to fiat
+func Value(amount *big.Int, decimals int64, quote Quote) *big.Rat {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(decimals), nil)
+	value := new(big.Rat).SetFrac(amount, unit)
+	return value.Mul(value, quote.Price)
+}
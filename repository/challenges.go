@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"time"
+)
+
+type ChallengeRepository struct {
+	tx *sqlx.Tx
+}
+
+// UpsertChallenge replaces any pending challenge of the user for the same address
+func (ch *ChallengeRepository) UpsertChallenge(challenge models.Challenge, ctx context.Context) error {
+	const query = `INSERT INTO challenges("address", "user_id", "challenge", "expires_at") VALUES($1,$2,$3,$4)
+		ON CONFLICT ("address", "user_id") DO UPDATE SET "challenge" = EXCLUDED."challenge", "expires_at" = EXCLUDED."expires_at"`
+
+	_, err := ch.tx.ExecContext(ctx, query, strings.ToLower(challenge.Address), challenge.UserID, challenge.Challenge, challenge.ExpiresAt)
+	return err
+}
+
+// ConsumeChallenge deletes and returns the pending challenge. Expired challenges are treated as not found.
+func (ch *ChallengeRepository) ConsumeChallenge(address, userID string, ctx context.Context) (models.Challenge, error) {
+	const query = `DELETE FROM challenges WHERE "address" = $1 AND "user_id" = $2 RETURNING *`
+
+	var challenge dbChallenge
+	err := ch.tx.GetContext(ctx, &challenge, query, strings.ToLower(address), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Challenge{}, common.ErrNotFound
+		}
+		return models.Challenge{}, fmt.Errorf("failed to get dbChallenge: %w", err)
+	}
+
+	if time.Now().Unix() > challenge.ExpiresAt {
+		return models.Challenge{}, common.ErrNotFound
+	}
+
+	return models.Challenge(challenge), nil
+}
+
+func (ch *ChallengeRepository) DeleteExpiredChallenges(ctx context.Context) (int64, error) {
+	const query = `DELETE FROM challenges WHERE "expires_at" < $1`
+
+	result, err := ch.tx.ExecContext(ctx, query, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
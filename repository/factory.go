@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"github.com/Leantar/elonwallet-backend/server/common"
 	"github.com/jmoiron/sqlx"
@@ -15,17 +16,6 @@ type TransactionFactory struct {
 	db *sqlx.DB
 }
 
-func (tf *TransactionFactory) migrate() error {
-	for _, schema := range schemas {
-		_, err := tf.db.Exec(schema)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func (tf *TransactionFactory) Begin() (common.Transaction, error) {
 	tx, err := tf.db.Beginx()
 	if err != nil {
@@ -41,12 +31,11 @@ func NewTransactionFactory(connectionString string) (*TransactionFactory, error)
 		return nil, fmt.Errorf("failed to connect to db: %w", err)
 	}
 
-	tf := TransactionFactory{db: db}
-
-	err = tf.migrate()
+	migrator := Migrator{db: db}
+	err = migrator.Up(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate db: %w", err)
 	}
 
-	return &tf, nil
+	return &TransactionFactory{db: db}, nil
 }
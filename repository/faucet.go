@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strings"
+)
+
+type FaucetRepository struct {
+	tx *sqlx.Tx
+}
+
+// LockDripTarget serializes faucet requests of the user and to the address until the transaction ends, so that neither limit can be raced.
+// The user is always locked first, so that two requests cannot deadlock.
+func (f *FaucetRepository) LockDripTarget(userID, address string, ctx context.Context) error {
+	const userQuery = `SELECT pg_advisory_xact_lock(hashtext('faucet:' || $1))`
+	const addressQuery = `SELECT pg_advisory_xact_lock(hashtext('faucet-address:' || $1))`
+
+	_, err := f.tx.ExecContext(ctx, userQuery, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.tx.ExecContext(ctx, addressQuery, strings.ToLower(address))
+	return err
+}
+
+// CountDripsSince counts the drips on the chain that went to the user or to the address since the given unix time.
+// Failed drips are not counted, pending ones are.
+func (f *FaucetRepository) CountDripsSince(userID, address string, chainID, since int64, ctx context.Context) (int64, error) {
+	const query = `SELECT COUNT(*) FROM faucet_drips WHERE ("user_id" = $1 OR "address" = $2) AND "chain_id" = $3 AND "created" >= $4 AND "status" <> $5`
+
+	var count int64
+	err := f.tx.GetContext(ctx, &count, query, userID, strings.ToLower(address), chainID, since, models.FaucetDripFailed)
+	return count, err
+}
+
+// FailAbandonedDrips fails pending drips of the user or to the address that never got a transaction, e.g. because the process stopped
+func (f *FaucetRepository) FailAbandonedDrips(userID, address string, chainID, createdBefore int64, ctx context.Context) error {
+	const query = `UPDATE faucet_drips SET "status" = $1 WHERE ("user_id" = $2 OR "address" = $3) AND "chain_id" = $4 AND "status" = $5 AND "tx_hash" = '' AND "created" < $6`
+
+	_, err := f.tx.ExecContext(ctx, query, models.FaucetDripFailed, userID, strings.ToLower(address), chainID, models.FaucetDripPending, createdBefore)
+	return err
+}
+
+func (f *FaucetRepository) CreateDrip(drip models.FaucetDrip, ctx context.Context) (int64, error) {
+	const query = `INSERT INTO faucet_drips("user_id", "address", "chain_id", "amount", "tx_hash", "status", "created") VALUES($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
+
+	var id int64
+	err := f.tx.GetContext(ctx, &id, query, drip.UserID, strings.ToLower(drip.Address), drip.ChainID, drip.Amount, strings.ToLower(drip.TxHash), drip.Status, drip.Created)
+	return id, err
+}
+
+// SetDripTransaction attaches the signed transaction to a pending drip. ErrNotFound is returned if the drip is no longer pending.
+func (f *FaucetRepository) SetDripTransaction(id int64, txHash string, ctx context.Context) error {
+	const query = `UPDATE faucet_drips SET "tx_hash" = $1 WHERE "id" = $2 AND "status" = $3 AND "tx_hash" = ''`
+
+	result, err := f.tx.ExecContext(ctx, query, strings.ToLower(txHash), id, models.FaucetDripPending)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
+
+// LockDrip returns the drip and locks it until the transaction ends
+func (f *FaucetRepository) LockDrip(id int64, ctx context.Context) (models.FaucetDrip, error) {
+	const query = `SELECT * FROM faucet_drips WHERE "id" = $1 FOR UPDATE`
+
+	var drip dbFaucetDrip
+	err := f.tx.GetContext(ctx, &drip, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.FaucetDrip{}, common.ErrNotFound
+		}
+		return models.FaucetDrip{}, fmt.Errorf("failed to get dbFaucetDrip: %w", err)
+	}
+
+	return models.FaucetDrip(drip), nil
+}
+
+func (f *FaucetRepository) SetDripStatus(id int64, status string, ctx context.Context) error {
+	const query = `UPDATE faucet_drips SET "status" = $1 WHERE "id" = $2`
+
+	_, err := f.tx.ExecContext(ctx, query, status, id)
+	return err
+}
+
+func (f *FaucetRepository) CreateJob(job models.FaucetJob, ctx context.Context) (int64, error) {
+	const query = `INSERT INTO faucet_jobs("user_id", "address", "chain_id", "status", "error", "tx_hash", "attempts", "run_after", "created", "updated")
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`
+
+	var id int64
+	err := f.tx.GetContext(ctx, &id, query, job.UserID, strings.ToLower(job.Address), job.ChainID, job.Status, job.Error, job.TxHash,
+		job.Attempts, job.RunAfter, job.Created, job.Updated)
+	return id, err
+}
+
+// ClaimNextJob locks the oldest due job until the transaction ends. Sending jobs are only due once their lease expired.
+// Jobs locked by another worker are skipped.
+func (f *FaucetRepository) ClaimNextJob(now int64, ctx context.Context) (models.FaucetJob, error) {
+	const query = `SELECT * FROM faucet_jobs WHERE "status" IN ($1, $2) AND "run_after" <= $3 ORDER BY "run_after" LIMIT 1 FOR UPDATE SKIP LOCKED`
+
+	var job dbFaucetJob
+	err := f.tx.GetContext(ctx, &job, query, models.FaucetJobQueued, models.FaucetJobSending, now)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.FaucetJob{}, common.ErrNotFound
+		}
+		return models.FaucetJob{}, fmt.Errorf("failed to get dbFaucetJob: %w", err)
+	}
+
+	return models.FaucetJob(job), nil
+}
+
+func (f *FaucetRepository) GetJob(id int64, userID string, ctx context.Context) (models.FaucetJob, error) {
+	const query = `SELECT * FROM faucet_jobs WHERE "id" = $1 AND "user_id" = $2`
+
+	var job dbFaucetJob
+	err := f.tx.GetContext(ctx, &job, query, id, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.FaucetJob{}, common.ErrNotFound
+		}
+		return models.FaucetJob{}, fmt.Errorf("failed to get dbFaucetJob: %w", err)
+	}
+
+	return models.FaucetJob(job), nil
+}
+
+func (f *FaucetRepository) UpdateJob(job models.FaucetJob, ctx context.Context) error {
+	const query = `UPDATE faucet_jobs SET "status" = $1, "error" = $2, "tx_hash" = $3, "drip_id" = $4, "attempts" = $5, "run_after" = $6, "updated" = $7 WHERE "id" = $8`
+
+	_, err := f.tx.ExecContext(ctx, query, job.Status, job.Error, job.TxHash, job.DripID, job.Attempts, job.RunAfter, job.Updated, job.ID)
+	return err
+}
+
+// ReplaceDripTransaction points drips and jobs that reference one of the old transactions to the new one
+func (f *FaucetRepository) ReplaceDripTransaction(oldHashes []string, newHash string, ctx context.Context) error {
+	const dripQuery = `UPDATE faucet_drips SET "tx_hash" = $1 WHERE "tx_hash" = ANY($2)`
+	const jobQuery = `UPDATE faucet_jobs SET "tx_hash" = $1 WHERE "tx_hash" = ANY($2)`
+
+	hashes := make([]string, len(oldHashes))
+	for i, hash := range oldHashes {
+		hashes[i] = strings.ToLower(hash)
+	}
+
+	_, err := f.tx.ExecContext(ctx, dripQuery, strings.ToLower(newHash), pq.StringArray(hashes))
+	if err != nil {
+		return err
+	}
+
+	_, err = f.tx.ExecContext(ctx, jobQuery, strings.ToLower(newHash), pq.StringArray(hashes))
+	return err
+}
+
+func (f *FaucetRepository) SetDripStatusByTransaction(txHash, status string, ctx context.Context) error {
+	const query = `UPDATE faucet_drips SET "status" = $1 WHERE "tx_hash" = $2`
+
+	_, err := f.tx.ExecContext(ctx, query, status, strings.ToLower(txHash))
+	return err
+}
+
+// FailJobsByTransaction fails the sent jobs whose transaction was dropped or reverted
+func (f *FaucetRepository) FailJobsByTransaction(txHash, reason string, updated int64, ctx context.Context) error {
+	const query = `UPDATE faucet_jobs SET "status" = $1, "error" = $2, "updated" = $3 WHERE "tx_hash" = $4 AND "status" = $5`
+
+	_, err := f.tx.ExecContext(ctx, query, models.FaucetJobFailed, reason, updated, strings.ToLower(txHash), models.FaucetJobSent)
+	return err
+}
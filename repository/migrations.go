@@ -0,0 +1,207 @@
+package repository
+
+type migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// migrations must be ordered by version. Never edit a migration that has already been released, add a new one instead.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up: `CREATE TABLE IF NOT EXISTS users(
+    	"id" TEXT PRIMARY KEY,
+    	"name" TEXT NOT NULL,
+    	"email" TEXT NOT NULL UNIQUE,
+    	"enclave_url" TEXT NOT NULL,
+    	"verification_key" TEXT NOT NULL);
+	CREATE TABLE IF NOT EXISTS wallets(
+    	"address" TEXT PRIMARY KEY,
+    	"name" TEXT NOT NULL,
+    	"user_id" TEXT NOT NULL,
+    	CONSTRAINT fk_user
+            FOREIGN KEY("user_id")
+    			REFERENCES users("id")
+    			ON DELETE CASCADE,
+    	UNIQUE("name", "user_id"));
+	CREATE TABLE IF NOT EXISTS contacts(
+  		"user_id" TEXT NOT NULL,
+  		"contact_id" TEXT NOT NULL,
+  		PRIMARY KEY ("user_id", "contact_id"),
+		CONSTRAINT fk_user
+			FOREIGN KEY("user_id")
+				REFERENCES users("id")
+				ON DELETE CASCADE,
+    	CONSTRAINT fk_contact
+			FOREIGN KEY("contact_id")
+				REFERENCES users("id")
+				ON DELETE CASCADE);
+	CREATE TABLE IF NOT EXISTS signups(
+  		"user_id" TEXT PRIMARY KEY,
+  		"activated" BOOLEAN NOT NULL,
+  		"activation_string" TEXT NOT NULL,
+  		"created" BIGINT NOT NULL,
+  		"valid_until" BIGINT NOT NULL,
+		CONSTRAINT fk_user
+			FOREIGN KEY("user_id")
+				REFERENCES users("id")
+				ON DELETE CASCADE);
+	CREATE TABLE IF NOT EXISTS notifications(
+    	"id" BIGSERIAL,
+    	"series_id" TEXT NOT NULL,
+  		"creation_time" BIGINT NOT NULL,
+  		"send_after" BIGINT NOT NULL,
+  		"times_tried" BIGINT NOT NULL,
+  		"user_id" TEXT NOT NULL,
+  		"title" TEXT NOT NULL,
+  		"body" TEXT NOT NULL,
+		CONSTRAINT fk_user
+			FOREIGN KEY("user_id")
+				REFERENCES users("id")
+				ON DELETE CASCADE);`,
+		Down: `DROP TABLE IF EXISTS notifications;
+	DROP TABLE IF EXISTS signups;
+	DROP TABLE IF EXISTS contacts;
+	DROP TABLE IF EXISTS wallets;
+	DROP TABLE IF EXISTS users;`,
+	},
+	{
+		Version: 2,
+		Name:    "wallets_lower_address_index",
+		Up:      `CREATE INDEX IF NOT EXISTS wallets_lower_address_idx ON wallets (LOWER("address"));`,
+		Down:    `DROP INDEX IF EXISTS wallets_lower_address_idx;`,
+	},
+	{
+		Version: 3,
+		Name:    "challenges",
+		Up: `CREATE TABLE IF NOT EXISTS challenges(
+		"address" TEXT NOT NULL,
+		"user_id" TEXT NOT NULL,
+		"challenge" TEXT NOT NULL,
+		"expires_at" BIGINT NOT NULL,
+		PRIMARY KEY ("address", "user_id"),
+		CONSTRAINT fk_user
+			FOREIGN KEY("user_id")
+				REFERENCES users("id")
+				ON DELETE CASCADE);
+	CREATE INDEX IF NOT EXISTS challenges_expires_at_idx ON challenges ("expires_at");`,
+		Down: `DROP TABLE IF EXISTS challenges;`,
+	},
+	{
+		Version: 4,
+		Name:    "wallet_chains",
+		// Wallets linked before chains were tracked were all used on Polygon Mumbai
+		Up: `ALTER TABLE wallets ADD COLUMN IF NOT EXISTS "chains" BIGINT[] NOT NULL DEFAULT '{}';
+	UPDATE wallets SET "chains" = '{80001}' WHERE "chains" = '{}';`,
+		Down: `ALTER TABLE wallets DROP COLUMN IF EXISTS "chains";`,
+	},
+	{
+		Version: 5,
+		Name:    "faucet_drips",
+		Up: `CREATE TABLE IF NOT EXISTS faucet_drips(
+		"id" BIGSERIAL PRIMARY KEY,
+		"user_id" TEXT NOT NULL,
+		"address" TEXT NOT NULL,
+		"chain_id" BIGINT NOT NULL,
+		"amount" TEXT NOT NULL,
+		"tx_hash" TEXT NOT NULL,
+		"created" BIGINT NOT NULL,
+		CONSTRAINT fk_user
+			FOREIGN KEY("user_id")
+				REFERENCES users("id")
+				ON DELETE CASCADE);
+	CREATE INDEX IF NOT EXISTS faucet_drips_user_idx ON faucet_drips ("user_id", "chain_id", "created");
+	CREATE INDEX IF NOT EXISTS faucet_drips_address_idx ON faucet_drips ("address", "chain_id", "created");`,
+		Down: `DROP TABLE IF EXISTS faucet_drips;`,
+	},
+	{
+		Version: 6,
+		Name:    "funding_nonces",
+		Up: `CREATE TABLE IF NOT EXISTS funding_nonces(
+		"address" TEXT NOT NULL,
+		"chain_id" BIGINT NOT NULL,
+		"next_nonce" BIGINT NOT NULL,
+		PRIMARY KEY ("address", "chain_id"));`,
+		Down: `DROP TABLE IF EXISTS funding_nonces;`,
+	},
+	{
+		Version: 7,
+		Name:    "outbound_transactions",
+		Up: `CREATE TABLE IF NOT EXISTS outbound_transactions(
+		"id" BIGSERIAL PRIMARY KEY,
+		"chain_id" BIGINT NOT NULL,
+		"hash" TEXT NOT NULL UNIQUE,
+		"nonce" BIGINT NOT NULL,
+		"from_address" TEXT NOT NULL,
+		"to_address" TEXT NOT NULL,
+		"value" TEXT NOT NULL,
+		"raw_tx" TEXT NOT NULL,
+		"status" TEXT NOT NULL,
+		"block_number" BIGINT NOT NULL DEFAULT 0,
+		"attempts" BIGINT NOT NULL DEFAULT 0,
+		"created" BIGINT NOT NULL,
+		"updated" BIGINT NOT NULL);
+	CREATE INDEX IF NOT EXISTS outbound_transactions_status_idx ON outbound_transactions ("status", "updated");`,
+		Down: `DROP TABLE IF EXISTS outbound_transactions;`,
+	},
+	{
+		Version: 8,
+		Name:    "faucet_jobs",
+		Up: `CREATE TABLE IF NOT EXISTS faucet_jobs(
+		"id" BIGSERIAL PRIMARY KEY,
+		"user_id" TEXT NOT NULL,
+		"address" TEXT NOT NULL,
+		"chain_id" BIGINT NOT NULL,
+		"status" TEXT NOT NULL,
+		"error" TEXT NOT NULL DEFAULT '',
+		"tx_hash" TEXT NOT NULL DEFAULT '',
+		"attempts" BIGINT NOT NULL DEFAULT 0,
+		"run_after" BIGINT NOT NULL,
+		"created" BIGINT NOT NULL,
+		"updated" BIGINT NOT NULL,
+		CONSTRAINT fk_user
+			FOREIGN KEY("user_id")
+				REFERENCES users("id")
+				ON DELETE CASCADE);
+	CREATE INDEX IF NOT EXISTS faucet_jobs_status_idx ON faucet_jobs ("status", "run_after");`,
+		Down: `DROP TABLE IF EXISTS faucet_jobs;`,
+	},
+	{
+		Version: 9,
+		Name:    "outbound_transactions_user",
+		// Transactions relayed for users are tracked alongside the ones of the funding wallet
+		Up: `ALTER TABLE outbound_transactions ADD COLUMN IF NOT EXISTS "user_id" TEXT REFERENCES users("id") ON DELETE CASCADE;
+	CREATE INDEX IF NOT EXISTS outbound_transactions_user_idx ON outbound_transactions ("user_id", "status");`,
+		Down: `ALTER TABLE outbound_transactions DROP COLUMN IF EXISTS "user_id";`,
+	},
+	{
+		Version: 10,
+		Name:    "watcher_cursors",
+		Up: `CREATE TABLE IF NOT EXISTS watcher_cursors(
+		"chain_id" BIGINT PRIMARY KEY,
+		"last_block" BIGINT NOT NULL,
+		"updated" BIGINT NOT NULL);`,
+		Down: `DROP TABLE IF EXISTS watcher_cursors;`,
+	},
+	{
+		Version: 11,
+		Name:    "faucet_drip_status",
+		// Drips are recorded before they are broadcast, so that coins are never sent without a record
+		Up: `ALTER TABLE faucet_drips ADD COLUMN IF NOT EXISTS "status" TEXT NOT NULL DEFAULT 'sent';
+	ALTER TABLE faucet_drips ALTER COLUMN "tx_hash" SET DEFAULT '';
+	CREATE INDEX IF NOT EXISTS faucet_drips_tx_hash_idx ON faucet_drips ("tx_hash");`,
+		Down: `DROP INDEX IF EXISTS faucet_drips_tx_hash_idx;
+	ALTER TABLE faucet_drips ALTER COLUMN "tx_hash" DROP DEFAULT;
+	ALTER TABLE faucet_drips DROP COLUMN IF EXISTS "status";`,
+	},
+	{
+		Version: 12,
+		Name:    "faucet_job_drip",
+		// Jobs remember their drip, so that a retried job reconciles the drip instead of sending again. 0 means no drip yet.
+		Up:   `ALTER TABLE faucet_jobs ADD COLUMN IF NOT EXISTS "drip_id" BIGINT NOT NULL DEFAULT 0;`,
+		Down: `ALTER TABLE faucet_jobs DROP COLUMN IF EXISTS "drip_id";`,
+	},
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const (
+	// migrationLockID is an arbitrary but fixed key for pg_advisory_lock, so that only one replica migrates at a time
+	migrationLockID = 7_310_455_901
+)
+
+var (
+	ErrNothingToRollback = errors.New("no applied migrations to roll back")
+)
+
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+	// Baseline is set if the schema of the migration exists but has not been recorded yet
+	Baseline  bool
+	AppliedAt int64
+}
+
+type dbSchemaMigration struct {
+	Version   int64  `db:"version"`
+	Name      string `db:"name"`
+	AppliedAt int64  `db:"applied_at"`
+}
+
+type Migrator struct {
+	db *sqlx.DB
+}
+
+func NewMigrator(connectionString string) (*Migrator, error) {
+	db, err := sqlx.Connect("postgres", connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to db: %w", err)
+	}
+
+	return &Migrator{db: db}, nil
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Up applies all pending migrations in order
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(conn, ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+
+			err = applyMigration(conn, mig.Up, ctx, func(tx *sqlx.Tx) error {
+				const query = `INSERT INTO schema_migrations("version", "name", "applied_at") VALUES($1,$2,$3)`
+				_, err := tx.ExecContext(ctx, query, mig.Version, mig.Name, time.Now().Unix())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d (%s): %w", mig.Version, mig.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(steps int, ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(conn, ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			return ErrNothingToRollback
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+
+			err = applyMigration(conn, mig.Down, ctx, func(tx *sqlx.Tx) error {
+				const query = `DELETE FROM schema_migrations WHERE "version" = $1`
+				_, err := tx.ExecContext(ctx, query, mig.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to roll back migration %d (%s): %w", mig.Version, mig.Name, err)
+			}
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Status lists every known migration and whether it has been applied.
+// It only reads, so neither the migration lock is taken nor the schema_migrations table is created.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	const existsQuery = `SELECT to_regclass('public.schema_migrations') IS NOT NULL`
+
+	var exists bool
+	err := m.db.GetContext(ctx, &exists, existsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect schema_migrations table: %w", err)
+	}
+
+	applied := make(map[int64]dbSchemaMigration)
+	if exists {
+		applied, err = appliedMigrations(m.db, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A legacy database gets the baseline recorded on the next migration, see ensureMigrationTable
+	legacy := false
+	if len(applied) == 0 {
+		legacy, err = isLegacySchema(m.db, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, mig := range migrations {
+		appliedMigration, ok := applied[mig.Version]
+		status[i] = MigrationStatus{
+			Version:   mig.Version,
+			Name:      mig.Name,
+			Applied:   ok,
+			Baseline:  legacy && i == 0,
+			AppliedAt: appliedMigration.AppliedAt,
+		}
+	}
+
+	return status, nil
+}
+
+// withLock runs fn on a dedicated connection while holding the migration advisory lock.
+// Advisory locks are bound to the session, which is why a single connection must be used throughout.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) (err error) {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+		if unlockErr != nil && err == nil {
+			err = fmt.Errorf("failed to release migration lock: %w", unlockErr)
+		}
+	}()
+
+	err = ensureMigrationTable(conn, ctx)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func ensureMigrationTable(conn *sqlx.Conn, ctx context.Context) error {
+	const createQuery = `CREATE TABLE IF NOT EXISTS schema_migrations(
+		"version" BIGINT PRIMARY KEY,
+		"name" TEXT NOT NULL,
+		"applied_at" BIGINT NOT NULL);`
+	const countQuery = `SELECT COUNT(*) FROM schema_migrations`
+	const baselineQuery = `INSERT INTO schema_migrations("version", "name", "applied_at") VALUES($1,$2,$3)`
+
+	_, err := conn.ExecContext(ctx, createQuery)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var count int64
+	err = conn.GetContext(ctx, &count, countQuery)
+	if err != nil {
+		return fmt.Errorf("failed to count applied migrations: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	isLegacy, err := isLegacySchema(conn, ctx)
+	if err != nil {
+		return err
+	}
+	if !isLegacy {
+		return nil
+	}
+
+	baseline := migrations[0]
+	_, err = conn.ExecContext(ctx, baselineQuery, baseline.Version, baseline.Name, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to mark baseline migration: %w", err)
+	}
+
+	return nil
+}
+
+// isLegacySchema detects databases created before versioned migrations existed, which already contain the baseline schema
+func isLegacySchema(q sqlx.QueryerContext, ctx context.Context) (bool, error) {
+	const query = `SELECT to_regclass('public.users') IS NOT NULL`
+
+	var isLegacy bool
+	err := sqlx.GetContext(ctx, q, &isLegacy, query)
+	if err != nil {
+		return false, fmt.Errorf("failed to detect legacy schema: %w", err)
+	}
+
+	return isLegacy, nil
+}
+
+func appliedMigrations(q sqlx.QueryerContext, ctx context.Context) (map[int64]dbSchemaMigration, error) {
+	const query = `SELECT * FROM schema_migrations`
+
+	rows := make([]dbSchemaMigration, 0)
+	err := sqlx.SelectContext(ctx, q, &rows, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]dbSchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+func applyMigration(conn *sqlx.Conn, statement string, ctx context.Context, record func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, statement)
+	if err == nil {
+		err = record(tx)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("failed to rollback tx: %w", rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
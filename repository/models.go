@@ -1,5 +1,10 @@
 package repository
 
+import (
+	"database/sql"
+	"github.com/lib/pq"
+)
+
 type dbUser struct {
 	ID              string `db:"id"`
 	Name            string `db:"name"`
@@ -9,9 +14,10 @@ type dbUser struct {
 }
 
 type dbWallet struct {
-	Address string `db:"address"`
-	Name    string `db:"name"`
-	UserID  string `db:"user_id"`
+	Address string        `db:"address"`
+	Name    string        `db:"name"`
+	UserID  string        `db:"user_id"`
+	Chains  pq.Int64Array `db:"chains"`
 }
 
 type dbContact struct {
@@ -37,3 +43,53 @@ type dbNotification struct {
 	Title        string `db:"title"`
 	Body         string `db:"body"`
 }
+
+type dbChallenge struct {
+	Address   string `db:"address"`
+	UserID    string `db:"user_id"`
+	Challenge string `db:"challenge"`
+	ExpiresAt int64  `db:"expires_at"`
+}
+
+type dbOutboundTransaction struct {
+	ID          int64          `db:"id"`
+	UserID      sql.NullString `db:"user_id"`
+	ChainID     int64          `db:"chain_id"`
+	Hash        string         `db:"hash"`
+	Nonce       int64          `db:"nonce"`
+	From        string         `db:"from_address"`
+	To          string         `db:"to_address"`
+	Value       string         `db:"value"`
+	RawTx       string         `db:"raw_tx"`
+	Status      string         `db:"status"`
+	BlockNumber int64          `db:"block_number"`
+	Attempts    int64          `db:"attempts"`
+	Created     int64          `db:"created"`
+	Updated     int64          `db:"updated"`
+}
+
+type dbFaucetDrip struct {
+	ID      int64  `db:"id"`
+	UserID  string `db:"user_id"`
+	Address string `db:"address"`
+	ChainID int64  `db:"chain_id"`
+	Amount  string `db:"amount"`
+	TxHash  string `db:"tx_hash"`
+	Status  string `db:"status"`
+	Created int64  `db:"created"`
+}
+
+type dbFaucetJob struct {
+	ID       int64  `db:"id"`
+	UserID   string `db:"user_id"`
+	Address  string `db:"address"`
+	ChainID  int64  `db:"chain_id"`
+	Status   string `db:"status"`
+	Error    string `db:"error"`
+	TxHash   string `db:"tx_hash"`
+	DripID   int64  `db:"drip_id"`
+	Attempts int64  `db:"attempts"`
+	RunAfter int64  `db:"run_after"`
+	Created  int64  `db:"created"`
+	Updated  int64  `db:"updated"`
+}
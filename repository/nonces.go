@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+type NonceRepository struct {
+	tx *sqlx.Tx
+}
+
+// LockNextNonce returns the next nonce and locks it until the transaction ends
+func (n *NonceRepository) LockNextNonce(address string, chainID int64, ctx context.Context) (uint64, error) {
+	const query = `SELECT "next_nonce" FROM funding_nonces WHERE "address" = $1 AND "chain_id" = $2 FOR UPDATE`
+
+	var nonce int64
+	err := n.tx.GetContext(ctx, &nonce, query, strings.ToLower(address), chainID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, common.ErrNotFound
+		}
+		return 0, fmt.Errorf("failed to get next nonce: %w", err)
+	}
+
+	return uint64(nonce), nil
+}
+
+// InitNextNonce stores the nonce unless another replica has already done so
+func (n *NonceRepository) InitNextNonce(address string, chainID int64, nonce uint64, ctx context.Context) error {
+	const query = `INSERT INTO funding_nonces("address", "chain_id", "next_nonce") VALUES($1,$2,$3) ON CONFLICT DO NOTHING`
+
+	_, err := n.tx.ExecContext(ctx, query, strings.ToLower(address), chainID, int64(nonce))
+	return err
+}
+
+func (n *NonceRepository) SetNextNonce(address string, chainID int64, nonce uint64, ctx context.Context) error {
+	const query = `UPDATE funding_nonces SET "next_nonce" = $1 WHERE "address" = $2 AND "chain_id" = $3`
+
+	_, err := n.tx.ExecContext(ctx, query, int64(nonce), strings.ToLower(address), chainID)
+	return err
+}
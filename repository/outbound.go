@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strings"
+)
+
+type OutboundRepository struct {
+	tx *sqlx.Tx
+}
+
+func (o *OutboundRepository) CreateOutboundTransaction(transaction models.OutboundTransaction, ctx context.Context) (int64, error) {
+	const query = `INSERT INTO outbound_transactions("user_id", "chain_id", "hash", "nonce", "from_address", "to_address", "value", "raw_tx", "status", "block_number", "attempts", "created", "updated")
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13) RETURNING "id"`
+
+	userID := sql.NullString{String: transaction.UserID, Valid: transaction.UserID != ""}
+
+	var id int64
+	err := o.tx.GetContext(ctx, &id, query, userID, transaction.ChainID, strings.ToLower(transaction.Hash), int64(transaction.Nonce),
+		strings.ToLower(transaction.From), strings.ToLower(transaction.To), transaction.Value, transaction.RawTx,
+		transaction.Status, int64(transaction.BlockNumber), transaction.Attempts, transaction.Created, transaction.Updated)
+	if e, ok := err.(*pq.Error); ok && e.Code == postgresUniqueViolationCode {
+		err = common.ErrConflict
+	}
+
+	return id, err
+}
+
+func (o *OutboundRepository) GetOutboundTransaction(hash string, ctx context.Context) (models.OutboundTransaction, error) {
+	const query = `SELECT * FROM outbound_transactions WHERE "hash" = $1`
+
+	var transaction dbOutboundTransaction
+	err := o.tx.GetContext(ctx, &transaction, query, strings.ToLower(hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.OutboundTransaction{}, common.ErrNotFound
+		}
+		return models.OutboundTransaction{}, err
+	}
+
+	return mapOutboundTransactions([]dbOutboundTransaction{transaction})[0], nil
+}
+
+// UpdateOutboundTransaction stores the result of a check. ErrNotFound is returned if the transaction is no longer pending.
+func (o *OutboundRepository) UpdateOutboundTransaction(transaction models.OutboundTransaction, ctx context.Context) error {
+	const query = `UPDATE outbound_transactions SET "status" = $1, "block_number" = $2, "attempts" = $3, "updated" = $4 WHERE "id" = $5 AND "status" = $6`
+
+	result, err := o.tx.ExecContext(ctx, query, transaction.Status, int64(transaction.BlockNumber), transaction.Attempts, transaction.Updated, transaction.ID, models.OutboundStatusPending)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
+
+func (o *OutboundRepository) SetOutboundTransactionStatus(hash, status string, updated int64, ctx context.Context) error {
+	const query = `UPDATE outbound_transactions SET "status" = $1, "updated" = $2 WHERE "hash" = $3`
+
+	_, err := o.tx.ExecContext(ctx, query, status, updated, strings.ToLower(hash))
+	return err
+}
+
+// ClaimPendingOutboundTransactions returns pending transactions that were not checked since checkedBefore
+// and marks them as checked now, so that other replicas skip them for the check interval
+func (o *OutboundRepository) ClaimPendingOutboundTransactions(checkedBefore, now, limit int64, ctx context.Context) ([]models.OutboundTransaction, error) {
+	const query = `UPDATE outbound_transactions SET "updated" = $1 WHERE "id" IN (
+		SELECT "id" FROM outbound_transactions WHERE "status" = $2 AND "updated" < $3 ORDER BY "updated" LIMIT $4 FOR UPDATE SKIP LOCKED)
+		RETURNING *`
+
+	transactions := make([]dbOutboundTransaction, 0)
+	err := o.tx.SelectContext(ctx, &transactions, query, now, models.OutboundStatusPending, checkedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapOutboundTransactions(transactions), nil
+}
+
+// GetHighestPendingNonce returns the highest nonce of the pending transactions of the sender, or ErrNotFound if there are none
+func (o *OutboundRepository) GetHighestPendingNonce(chainID int64, from string, ctx context.Context) (uint64, error) {
+	const query = `SELECT MAX("nonce") FROM outbound_transactions WHERE "chain_id" = $1 AND "from_address" = $2 AND "status" = $3`
+
+	var nonce sql.NullInt64
+	err := o.tx.GetContext(ctx, &nonce, query, chainID, strings.ToLower(from), models.OutboundStatusPending)
+	if err != nil {
+		return 0, err
+	}
+	if !nonce.Valid {
+		return 0, common.ErrNotFound
+	}
+
+	return uint64(nonce.Int64), nil
+}
+
+// GetPendingOutboundTransactionsByNonce returns the pending transactions that compete for the nonce, oldest first
+func (o *OutboundRepository) GetPendingOutboundTransactionsByNonce(chainID int64, from string, nonce uint64, ctx context.Context) ([]models.OutboundTransaction, error) {
+	const query = `SELECT * FROM outbound_transactions WHERE "chain_id" = $1 AND "from_address" = $2 AND "nonce" = $3 AND "status" = $4 ORDER BY "id"`
+
+	transactions := make([]dbOutboundTransaction, 0)
+	err := o.tx.SelectContext(ctx, &transactions, query, chainID, strings.ToLower(from), int64(nonce), models.OutboundStatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapOutboundTransactions(transactions), nil
+}
+
+// GetOutboundTransactions returns the newest transactions first. An empty status matches all transactions.
+func (o *OutboundRepository) GetOutboundTransactions(status string, limit, offset int64, ctx context.Context) ([]models.OutboundTransaction, error) {
+	const query = `SELECT * FROM outbound_transactions WHERE ($1 = '' OR "status" = $1) ORDER BY "id" DESC LIMIT $2 OFFSET $3`
+
+	transactions := make([]dbOutboundTransaction, 0)
+	err := o.tx.SelectContext(ctx, &transactions, query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapOutboundTransactions(transactions), nil
+}
+
+// GetOutboundTransactionsOfUser returns the newest transactions relayed for the user first. An empty status matches all transactions.
+func (o *OutboundRepository) GetOutboundTransactionsOfUser(userID, status string, limit int64, ctx context.Context) ([]models.OutboundTransaction, error) {
+	const query = `SELECT * FROM outbound_transactions WHERE "user_id" = $1 AND ($2 = '' OR "status" = $2) ORDER BY "id" DESC LIMIT $3`
+
+	transactions := make([]dbOutboundTransaction, 0)
+	err := o.tx.SelectContext(ctx, &transactions, query, userID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapOutboundTransactions(transactions), nil
+}
+
+func mapOutboundTransactions(transactions []dbOutboundTransaction) []models.OutboundTransaction {
+	mapped := make([]models.OutboundTransaction, len(transactions))
+	for i, t := range transactions {
+		mapped[i] = models.OutboundTransaction{
+			ID:          t.ID,
+			UserID:      t.UserID.String,
+			ChainID:     t.ChainID,
+			Hash:        t.Hash,
+			Nonce:       uint64(t.Nonce),
+			From:        t.From,
+			To:          t.To,
+			Value:       t.Value,
+			RawTx:       t.RawTx,
+			Status:      t.Status,
+			BlockNumber: uint64(t.BlockNumber),
+			Attempts:    t.Attempts,
+			Created:     t.Created,
+			Updated:     t.Updated,
+		}
+	}
+	return mapped
+}
@@ -37,3 +37,23 @@ func (t *Transaction) Signups() common.SignupRepository {
 func (t *Transaction) Notifications() common.NotificationRepository {
 	return &NotificationRepository{tx: t.tx}
 }
+
+func (t *Transaction) Challenges() common.ChallengeRepository {
+	return &ChallengeRepository{tx: t.tx}
+}
+
+func (t *Transaction) Faucet() common.FaucetRepository {
+	return &FaucetRepository{tx: t.tx}
+}
+
+func (t *Transaction) Nonces() common.NonceRepository {
+	return &NonceRepository{tx: t.tx}
+}
+
+func (t *Transaction) Outbound() common.OutboundRepository {
+	return &OutboundRepository{tx: t.tx}
+}
+
+func (t *Transaction) Watcher() common.WatcherRepository {
+	return &WatcherRepository{tx: t.tx}
+}
@@ -42,9 +42,9 @@ func (u *UserRepository) RemoveUser(userID string, ctx context.Context) error {
 }
 
 func (u *UserRepository) AddWalletToUser(userID string, wallet models.Wallet, ctx context.Context) error {
-	const query = `INSERT INTO wallets("address", "name", "user_id") VALUES($1,$2,$3)`
+	const query = `INSERT INTO wallets("address", "name", "user_id", "chains") VALUES($1,$2,$3,$4)`
 
-	_, err := u.tx.ExecContext(ctx, query, wallet.Address, wallet.Name, userID)
+	_, err := u.tx.ExecContext(ctx, query, wallet.Address, wallet.Name, userID, pq.Int64Array(wallet.Chains))
 	if e, ok := err.(*pq.Error); ok && e.Code == postgresUniqueViolationCode {
 		err = common.ErrConflict
 	}
@@ -52,6 +52,141 @@ func (u *UserRepository) AddWalletToUser(userID string, wallet models.Wallet, ct
 	return err
 }
 
+func (u *UserRepository) GetWalletOwnerID(address string, ctx context.Context) (string, error) {
+	const query = `SELECT "user_id" FROM wallets WHERE LOWER("address") = LOWER($1)`
+
+	var userID string
+	err := u.tx.GetContext(ctx, &userID, query, address)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", common.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get wallet owner: %w", err)
+	}
+
+	return userID, nil
+}
+
+// GetWalletOwnersOnChain maps the lowercased addresses of all wallets used on the chain to the ids of their owners
+func (u *UserRepository) GetWalletOwnersOnChain(chainID int64, ctx context.Context) (map[string]string, error) {
+	const query = `SELECT LOWER("address") AS "address", "user_id" FROM wallets WHERE $1 = ANY("chains")`
+
+	rows := make([]struct {
+		Address string `db:"address"`
+		UserID  string `db:"user_id"`
+	}, 0)
+	err := u.tx.SelectContext(ctx, &rows, query, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet owners: %w", err)
+	}
+
+	owners := make(map[string]string, len(rows))
+	for _, row := range rows {
+		owners[row.Address] = row.UserID
+	}
+
+	return owners, nil
+}
+
+// GetAddressLabels maps the lowercased addresses of the user's wallets to their names
+// and the addresses of the contacts' wallets to the names of the contacts
+func (u *UserRepository) GetAddressLabels(userID string, ctx context.Context) (map[string]string, error) {
+	const query = `SELECT LOWER(w."address") AS "address", CASE WHEN w."user_id" = $1 THEN w."name" ELSE u."name" END AS "label"
+		FROM wallets w JOIN users u ON u."id" = w."user_id"
+		WHERE w."user_id" = $1 OR w."user_id" IN (SELECT "contact_id" FROM contacts WHERE "user_id" = $1)`
+
+	rows := make([]struct {
+		Address string `db:"address"`
+		Label   string `db:"label"`
+	}, 0)
+	err := u.tx.SelectContext(ctx, &rows, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address labels: %w", err)
+	}
+
+	labels := make(map[string]string, len(rows))
+	for _, row := range rows {
+		labels[row.Address] = row.Label
+	}
+
+	return labels, nil
+}
+
+func (u *UserRepository) GetWalletsOfUser(userID string, ctx context.Context) ([]models.Wallet, error) {
+	const query = `SELECT * FROM wallets WHERE "user_id" = $1`
+
+	wallets := make([]dbWallet, 0)
+	err := u.tx.SelectContext(ctx, &wallets, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dbWallets: %w", err)
+	}
+
+	return mapWallets(wallets), nil
+}
+
+func (u *UserRepository) RenameWallet(userID, address, name string, ctx context.Context) error {
+	const query = `UPDATE wallets SET "name" = $1 WHERE LOWER("address") = LOWER($2) AND "user_id" = $3`
+
+	result, err := u.tx.ExecContext(ctx, query, name, address, userID)
+	if e, ok := err.(*pq.Error); ok && e.Code == postgresUniqueViolationCode {
+		return common.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
+
+func (u *UserRepository) SetWalletChains(userID, address string, chains []int64, ctx context.Context) error {
+	const query = `UPDATE wallets SET "chains" = $1 WHERE LOWER("address") = LOWER($2) AND "user_id" = $3`
+
+	result, err := u.tx.ExecContext(ctx, query, pq.Int64Array(chains), address, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
+
+func (u *UserRepository) RemoveWalletFromUser(userID, address string, ctx context.Context) error {
+	const query = `DELETE FROM wallets WHERE LOWER("address") = LOWER($1) AND "user_id" = $2`
+
+	result, err := u.tx.ExecContext(ctx, query, address, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return common.ErrNotFound
+	}
+
+	return nil
+}
+
 func (u *UserRepository) AddContactToUser(userID, contactID string, ctx context.Context) error {
 	const query = `INSERT INTO contacts("user_id", "contact_id") VALUES($1,$2)`
 
@@ -167,9 +302,13 @@ func (u *UserRepository) GetUserByEmail(email string, ctx context.Context) (mode
 func mapWallets(wallets []dbWallet) []models.Wallet {
 	mapped := make([]models.Wallet, len(wallets))
 	for i, wallet := range wallets {
+		if wallet.Chains == nil {
+			wallet.Chains = make(pq.Int64Array, 0)
+		}
 		mapped[i] = models.Wallet{
 			Name:    wallet.Name,
 			Address: wallet.Address,
+			Chains:  wallet.Chains,
 		}
 	}
 	return mapped
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type WatcherRepository struct {
+	tx *sqlx.Tx
+}
+
+// GetCursor returns the last processed block of the chain without locking it
+func (w *WatcherRepository) GetCursor(chainID int64, ctx context.Context) (uint64, error) {
+	const query = `SELECT "last_block" FROM watcher_cursors WHERE "chain_id" = $1`
+
+	var lastBlock int64
+	err := w.tx.GetContext(ctx, &lastBlock, query, chainID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, common.ErrNotFound
+		}
+		return 0, fmt.Errorf("failed to get cursor: %w", err)
+	}
+
+	return uint64(lastBlock), nil
+}
+
+// LockCursor returns the last processed block of the chain and locks it until the transaction ends.
+// A cursor locked by another replica is reported as common.ErrConflict.
+func (w *WatcherRepository) LockCursor(chainID int64, ctx context.Context) (uint64, error) {
+	const query = `SELECT "last_block" FROM watcher_cursors WHERE "chain_id" = $1 FOR UPDATE SKIP LOCKED`
+	const existsQuery = `SELECT EXISTS(SELECT 1 FROM watcher_cursors WHERE "chain_id" = $1)`
+
+	var lastBlock int64
+	err := w.tx.GetContext(ctx, &lastBlock, query, chainID)
+	if err == nil {
+		return uint64(lastBlock), nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get cursor: %w", err)
+	}
+
+	var exists bool
+	err = w.tx.GetContext(ctx, &exists, existsQuery, chainID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check cursor: %w", err)
+	}
+	if exists {
+		return 0, common.ErrConflict
+	}
+
+	return 0, common.ErrNotFound
+}
+
+// InitCursor stores the cursor unless another replica has already done so
+func (w *WatcherRepository) InitCursor(chainID int64, lastBlock uint64, ctx context.Context) error {
+	const query = `INSERT INTO watcher_cursors("chain_id", "last_block", "updated") VALUES($1,$2,$3) ON CONFLICT DO NOTHING`
+
+	_, err := w.tx.ExecContext(ctx, query, chainID, int64(lastBlock), time.Now().Unix())
+	return err
+}
+
+func (w *WatcherRepository) SetCursor(chainID int64, lastBlock uint64, ctx context.Context) error {
+	const query = `UPDATE watcher_cursors SET "last_block" = $1, "updated" = $2 WHERE "chain_id" = $3`
+
+	_, err := w.tx.ExecContext(ctx, query, int64(lastBlock), time.Now().Unix(), chainID)
+	return err
+}
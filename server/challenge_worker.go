@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+func (s *Server) workOnExpiredChallenges() {
+	ctx := context.Background()
+	for {
+		time.Sleep(15 * time.Minute)
+
+		tx, err := s.tf.Begin()
+		if err != nil {
+			log.Error().Caller().Err(err).Msg("failed to start transaction")
+			continue
+		}
+
+		n, err := tx.Challenges().DeleteExpiredChallenges(ctx)
+		if err != nil {
+			log.Error().Caller().Err(err).Msg("failed to delete expired challenges")
+			if err := tx.Rollback(); err != nil {
+				log.Fatal().Caller().Err(err).Msg("failed to rollback tx")
+			}
+			continue
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Error().Caller().Err(err).Msg("failed to commit tx")
+			continue
+		}
+
+		log.Debug().Caller().Int64("count", n).Msg("deleted expired challenges")
+	}
+}
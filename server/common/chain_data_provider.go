@@ -0,0 +1,28 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"github.com/Leantar/elonwallet-backend/models"
+	"time"
+)
+
+var (
+	ErrUnsupportedChain = errors.New("chain is not supported")
+)
+
+// ChainDataProvider looks up on-chain data. Chains are identified by their 0x prefixed hex id.
+type ChainDataProvider interface {
+	GetBalance(address, chain string, ctx context.Context) (string, error)
+	GetTransactions(address, chain string, filter models.PageFilter, ctx context.Context) (models.TransactionPage, error)
+	GetTokenBalances(address, chain string, ctx context.Context) ([]models.TokenBalance, error)
+	GetTokenTransfers(address, chain string, filter models.PageFilter, ctx context.Context) (models.TokenTransferPage, error)
+	GetNFTs(address, chain string, filter models.PageFilter, ctx context.Context) (models.NFTPage, error)
+}
+
+// ChainDataCache stores encoded chain data lookups. Implementations must be safe for concurrent use,
+// so that a store shared between replicas can be used instead of the in-memory one.
+type ChainDataCache interface {
+	Get(key string, ctx context.Context) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration, ctx context.Context) error
+}
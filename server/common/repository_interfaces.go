@@ -19,6 +19,54 @@ type NotificationRepository interface {
 	DeleteNotificationSeries(seriesID string, userID string, ctx context.Context) error
 }
 
+type ChallengeRepository interface {
+	UpsertChallenge(challenge models.Challenge, ctx context.Context) error
+	ConsumeChallenge(address, userID string, ctx context.Context) (models.Challenge, error)
+	DeleteExpiredChallenges(ctx context.Context) (int64, error)
+}
+
+type FaucetRepository interface {
+	LockDripTarget(userID, address string, ctx context.Context) error
+	CountDripsSince(userID, address string, chainID, since int64, ctx context.Context) (int64, error)
+	FailAbandonedDrips(userID, address string, chainID, createdBefore int64, ctx context.Context) error
+	CreateDrip(drip models.FaucetDrip, ctx context.Context) (int64, error)
+	SetDripTransaction(id int64, txHash string, ctx context.Context) error
+	LockDrip(id int64, ctx context.Context) (models.FaucetDrip, error)
+	SetDripStatus(id int64, status string, ctx context.Context) error
+	ReplaceDripTransaction(oldHashes []string, newHash string, ctx context.Context) error
+	SetDripStatusByTransaction(txHash, status string, ctx context.Context) error
+	FailJobsByTransaction(txHash, reason string, updated int64, ctx context.Context) error
+	CreateJob(job models.FaucetJob, ctx context.Context) (int64, error)
+	ClaimNextJob(now int64, ctx context.Context) (models.FaucetJob, error)
+	GetJob(id int64, userID string, ctx context.Context) (models.FaucetJob, error)
+	UpdateJob(job models.FaucetJob, ctx context.Context) error
+}
+
+type NonceRepository interface {
+	LockNextNonce(address string, chainID int64, ctx context.Context) (uint64, error)
+	InitNextNonce(address string, chainID int64, nonce uint64, ctx context.Context) error
+	SetNextNonce(address string, chainID int64, nonce uint64, ctx context.Context) error
+}
+
+type OutboundRepository interface {
+	CreateOutboundTransaction(transaction models.OutboundTransaction, ctx context.Context) (int64, error)
+	UpdateOutboundTransaction(transaction models.OutboundTransaction, ctx context.Context) error
+	SetOutboundTransactionStatus(hash, status string, updated int64, ctx context.Context) error
+	GetOutboundTransaction(hash string, ctx context.Context) (models.OutboundTransaction, error)
+	ClaimPendingOutboundTransactions(checkedBefore, now, limit int64, ctx context.Context) ([]models.OutboundTransaction, error)
+	GetHighestPendingNonce(chainID int64, from string, ctx context.Context) (uint64, error)
+	GetPendingOutboundTransactionsByNonce(chainID int64, from string, nonce uint64, ctx context.Context) ([]models.OutboundTransaction, error)
+	GetOutboundTransactions(status string, limit, offset int64, ctx context.Context) ([]models.OutboundTransaction, error)
+	GetOutboundTransactionsOfUser(userID, status string, limit int64, ctx context.Context) ([]models.OutboundTransaction, error)
+}
+
+type WatcherRepository interface {
+	GetCursor(chainID int64, ctx context.Context) (uint64, error)
+	LockCursor(chainID int64, ctx context.Context) (uint64, error)
+	InitCursor(chainID int64, lastBlock uint64, ctx context.Context) error
+	SetCursor(chainID int64, lastBlock uint64, ctx context.Context) error
+}
+
 type SignupRepository interface {
 	CreateSignup(signup models.Signup, ctx context.Context) error
 	UpdateSignup(signup models.Signup, ctx context.Context) error
@@ -29,6 +77,13 @@ type UserRepository interface {
 	CreateUser(user models.User, ctx context.Context) error
 	RemoveUser(userID string, ctx context.Context) error
 	AddWalletToUser(userID string, wallet models.Wallet, ctx context.Context) error
+	GetWalletOwnerID(address string, ctx context.Context) (string, error)
+	GetWalletOwnersOnChain(chainID int64, ctx context.Context) (map[string]string, error)
+	GetWalletsOfUser(userID string, ctx context.Context) ([]models.Wallet, error)
+	GetAddressLabels(userID string, ctx context.Context) (map[string]string, error)
+	RenameWallet(userID, address, name string, ctx context.Context) error
+	SetWalletChains(userID, address string, chains []int64, ctx context.Context) error
+	RemoveWalletFromUser(userID, address string, ctx context.Context) error
 	AddContactToUser(userID, contactID string, ctx context.Context) error
 	RemoveContactFromUser(userID, contactID string, ctx context.Context) error
 	GetUserByID(userID string, ctx context.Context) (models.User, error)
@@ -42,6 +97,11 @@ type Transaction interface {
 	Users() UserRepository
 	Signups() SignupRepository
 	Notifications() NotificationRepository
+	Challenges() ChallengeRepository
+	Faucet() FaucetRepository
+	Nonces() NonceRepository
+	Outbound() OutboundRepository
+	Watcher() WatcherRepository
 }
 
 type TransactionFactory interface {
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const (
+	faucetJobsPerRound = 20
+)
+
+func (s *Server) workOnFaucetJobs() {
+	ctx := context.Background()
+	for {
+		time.Sleep(5 * time.Second)
+
+		for i := 0; i < faucetJobsPerRound; i++ {
+			found, err := s.faucet.ProcessNextJob(ctx)
+			if err != nil {
+				log.Error().Caller().Err(err).Msg("failed to process faucet job")
+				break
+			}
+			if !found {
+				break
+			}
+		}
+	}
+}
@@ -2,26 +2,38 @@ package handlers
 
 import (
 	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/ens"
+	"github.com/Leantar/elonwallet-backend/faucet"
+	"github.com/Leantar/elonwallet-backend/gas"
+	"github.com/Leantar/elonwallet-backend/prices"
 	"github.com/Leantar/elonwallet-backend/server/common"
-	"sync"
+	"github.com/Leantar/elonwallet-backend/txmanager"
 )
 
-type ErrorResponse struct {
-	Message string `json:"message"`
-}
-
 type Api struct {
-	tf         common.TransactionFactory
-	cfg        config.Config
-	challenges map[string]string //Holds the address of the wallet as the key and the personal sign message challenge as the value. Used to verify ownership of a wallet
-	mu         sync.Mutex
+	tf        common.TransactionFactory
+	cfg       config.Config
+	chainData common.ChainDataProvider
+	faucet    *faucet.Service
+	clients   *txmanager.ClientPool
+	gas       *gas.Oracle
+	prices    *prices.Service
+	ens       *ens.Resolver
+	// portfolioSlots bounds the concurrent provider requests of all portfolio requests
+	portfolioSlots chan struct{}
 }
 
-func NewApi(tf common.TransactionFactory, config config.Config) *Api {
+func NewApi(tf common.TransactionFactory, config config.Config, chainData common.ChainDataProvider, faucet *faucet.Service, clients *txmanager.ClientPool, gas *gas.Oracle, prices *prices.Service, ens *ens.Resolver) *Api {
 	return &Api{
-		tf:         tf,
-		cfg:        config,
-		challenges: make(map[string]string),
-		mu:         sync.Mutex{},
+		tf:        tf,
+		cfg:       config,
+		chainData: chainData,
+		faucet:    faucet,
+		clients:   clients,
+		gas:       gas,
+		prices:    prices,
+		ens:       ens,
+
+		portfolioSlots: make(chan struct{}, portfolioConcurrency),
 	}
 }
@@ -1,30 +1,75 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/ethutil"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/rs/zerolog/log"
-	"math/big"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
 )
 
 const (
-	MumbaiRPC = "https://rpc-mumbai.maticvigil.com/"
+	MumbaiChainID = 80001
+	// Bounds the node lookups of contract wallet signatures
+	signatureTimeout = 10 * time.Second
+	eip1271ABIJSON   = `[{"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],"name":"isValidSignature","outputs":[{"name":"magicValue","type":"bytes4"}],"stateMutability":"view","type":"function"}]`
 )
 
+var (
+	eip1271ABI        = ethutil.MustParseABI(eip1271ABIJSON)
+	eip1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+)
+
+// verifySignature accepts EOA signatures as well as EIP-1271 signatures of smart contract wallets.
+// The node is only asked if the signature is not a valid EOA signature of the address.
+func (a *Api) verifySignature(message, signature, address string, chain config.ChainConfig, ctx context.Context) (bool, error) {
+	valid, err := verifyPersonalSignature(message, signature, address)
+	if err == nil && valid {
+		return true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, signatureTimeout)
+	defer cancel()
+
+	client, err := a.clients.GetByURL(chain.SignatureRPC(), ctx)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusBadGateway, "Failed to verify signature").SetInternal(err)
+	}
+
+	code, err := client.CodeAt(ctx, common.HexToAddress(address), nil)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusBadGateway, "Failed to verify signature").SetInternal(fmt.Errorf("failed to get code: %w", err))
+	}
+	if len(code) == 0 {
+		return false, nil
+	}
+
+	valid, err = verifyContractSignature(client, message, signature, address, ctx)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusBadGateway, "Failed to verify signature").SetInternal(err)
+	}
+
+	return valid, nil
+}
+
 func verifyPersonalSignature(message, signature, address string) (bool, error) {
-	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
-	hash := crypto.Keccak256Hash([]byte(msg))
+	hash := hashPersonalMessage(message)
 
 	sig, err := hexutil.Decode(signature)
 	if err != nil {
 		return false, err
 	}
+	if len(sig) != crypto.SignatureLength {
+		return false, fmt.Errorf("invalid signature length: %d", len(sig))
+	}
 
 	// Last byte from personal sign is incremented by 27. We must decrement it to get the recovery id
 	// See https://stackoverflow.com/questions/69762108/implementing-ethereum-personal-sign-eip-191-from-go-ethereum-gives-different-s for more info
@@ -45,76 +90,34 @@ func verifyPersonalSignature(message, signature, address string) (bool, error) {
 	return valid && pkHex == pkSigHex, nil
 }
 
-func createTransaction(client *ethclient.Client, from, to string, ctx context.Context) (*types.Transaction, error) {
-	sender := common.HexToAddress(from)
-	receiver := common.HexToAddress(to)
-	value := new(big.Int).SetInt64(10000000000000000) //0.01 Matic
-	chain := new(big.Int).SetInt64(80001)             //Mumbai Test Network
-
-	feeCap, err := client.SuggestGasPrice(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to suggest fee cap: %w", err)
-	}
-
-	nonce, err := client.PendingNonceAt(ctx, sender)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get nonce: %w", err)
-	}
-
-	tipCap, err := client.SuggestGasTipCap(ctx)
+// verifyContractSignature calls isValidSignature on the wallet contract
+// See https://eips.ethereum.org/EIPS/eip-1271 for more info
+func verifyContractSignature(client *ethclient.Client, message, signature, address string, ctx context.Context) (bool, error) {
+	sig, err := hexutil.Decode(signature)
 	if err != nil {
-		return nil, fmt.Errorf("failed to suggest tipCap cap: %w", err)
-	}
-
-	dynTx := &types.DynamicFeeTx{
-		ChainID:   chain,
-		Nonce:     nonce,
-		GasFeeCap: feeCap,
-		GasTipCap: tipCap,
-		Gas:       21000,
-		To:        &receiver,
-		Value:     value,
-		Data:      make([]byte, 0),
+		return false, err
 	}
 
-	tx := types.NewTx(dynTx)
-	return tx, nil
-}
-
-func signTransaction(tx *types.Transaction, privateKeyHex string) (*types.Transaction, error) {
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	hash := hashPersonalMessage(message)
+	data, err := eip1271ABI.Pack("isValidSignature", hash, sig)
 	if err != nil {
-		log.Fatal().Caller().Err(err).Msg("failed to convert hex to private key")
+		return false, fmt.Errorf("failed to pack isValidSignature call: %w", err)
 	}
 
-	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(tx.ChainId()), privateKey)
+	contract := common.HexToAddress(address)
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign tx: %w", err)
+		// Contracts may revert on invalid signatures. Any other error is a problem of the node.
+		if ethutil.IsRevert(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to call isValidSignature: %w", err)
 	}
 
-	return signedTx, nil
+	return len(result) >= 4 && bytes.Equal(result[:4], eip1271MagicValue), nil
 }
 
-func sendMumbaiTestMatic(to string, cfg config.WalletConfig, ctx context.Context) error {
-	client, err := ethclient.DialContext(ctx, MumbaiRPC)
-	if err != nil {
-		return fmt.Errorf("failed to dial rpc: %w", err)
-	}
-
-	tx, err := createTransaction(client, cfg.Address, to, ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create tx: %w", err)
-	}
-
-	signedTx, err := signTransaction(tx, cfg.PrivateKeyHex)
-	if err != nil {
-		return fmt.Errorf("failed to sign tx: %w", err)
-	}
-
-	err = client.SendTransaction(ctx, signedTx)
-	if err != nil {
-		return fmt.Errorf("failed to send tx: %w", err)
-	}
-
-	return nil
+func hashPersonalMessage(message string) common.Hash {
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
+	return crypto.Keccak256Hash([]byte(msg))
 }
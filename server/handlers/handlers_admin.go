@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+func (a *Api) HandleGetOutboundTransactions() echo.HandlerFunc {
+	type input struct {
+		Status string `query:"status" validate:"omitempty,oneof=pending confirmed failed replaced dropped"`
+		Limit  int64  `query:"limit" validate:"gte=0,lte=100"`
+		Offset int64  `query:"offset" validate:"gte=0"`
+	}
+
+	type output struct {
+		Transactions []models.OutboundTransaction `json:"transactions"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		if in.Limit == 0 {
+			in.Limit = defaultPageLimit
+		}
+
+		tx := c.Get("tx").(common.Transaction)
+
+		transactions, err := tx.Outbound().GetOutboundTransactions(in.Status, in.Limit, in.Offset, c.Request().Context())
+		if err != nil {
+			return fmt.Errorf("failed to get outbound transactions: %w", err)
+		}
+
+		return c.JSON(http.StatusOK, output{Transactions: transactions})
+	}
+}
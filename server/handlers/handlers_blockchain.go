@@ -1,50 +1,44 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/prices"
+	"github.com/Leantar/elonwallet-backend/server/common"
 	"github.com/labstack/echo/v4"
+	"math/big"
 	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	// All supported chains are EVM chains whose native coin has 18 decimals
+	nativeDecimals = 18
 )
 
 func (a *Api) HandleGetTransactions() echo.HandlerFunc {
 	type input struct {
-		Address string `param:"address" validate:"required,ethereum_address"`
-		Chain   string `query:"chain" validate:"required"`
-	}
-
-	type Transaction struct {
-		Hash                     string  `json:"hash"`
-		Nonce                    string  `json:"nonce"`
-		TransactionIndex         string  `json:"transaction_index"`
-		FromAddress              string  `json:"from_address"`
-		ToAddress                string  `json:"to_address"`
-		Value                    string  `json:"value"`
-		Gas                      string  `json:"gas"`
-		GasPrice                 string  `json:"gas_price"`
-		Input                    string  `json:"input"`
-		ReceiptCumulativeGasUsed string  `json:"receipt_cumulative_gas_used"`
-		ReceiptGasUsed           string  `json:"receipt_gas_used"`
-		ReceiptContractAddress   string  `json:"receipt_contract_address"`
-		ReceiptRoot              string  `json:"receipt_root"`
-		ReceiptStatus            string  `json:"receipt_status"`
-		BlockTimestamp           string  `json:"block_timestamp"`
-		BlockNumber              string  `json:"block_number"`
-		BlockHash                string  `json:"block_hash"`
-		TransferIndex            []int64 `json:"transfer_index"`
-	}
-
-	type moralisResponse struct {
-		Total    int64         `json:"total"`
-		PageSize int64         `json:"page_size"`
-		Page     int64         `json:"page"`
-		Cursor   string        `json:"cursor"`
-		Result   []Transaction `json:"result"`
+		Address   string `param:"address" validate:"required,ethereum_address"`
+		Chain     string `query:"chain" validate:"required,chain"`
+		Cursor    string `query:"cursor" validate:"max=4096"`
+		Limit     int64  `query:"limit" validate:"gte=0,lte=100"`
+		FromBlock uint64 `query:"from_block"`
+		ToBlock   uint64 `query:"to_block" validate:"omitempty,gtefield=FromBlock"`
+		FromDate  string `query:"from_date" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		ToDate    string `query:"to_date" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 	}
 
 	type output struct {
-		Transactions []Transaction `json:"transactions"`
-		Total        int64         `json:"total"`
+		Transactions []models.Transaction `json:"transactions"`
+		Total        int64                `json:"total"`
+		Cursor       string               `json:"cursor"`
 	}
 
 	return func(c echo.Context) error {
@@ -56,31 +50,115 @@ func (a *Api) HandleGetTransactions() echo.HandlerFunc {
 			return err
 		}
 
-		moralisUrl := fmt.Sprintf("https://deep-index.moralis.io/api/v2/%s?chain=%s&limit=50&disable_total=true", in.Address, in.Chain)
+		filter, err := newPageFilter(in.Cursor, in.Limit, in.FromBlock, in.ToBlock, in.FromDate, in.ToDate)
+		if err != nil {
+			return err
+		}
+
+		page, err := a.chainData.GetTransactions(in.Address, a.chainID(in.Chain), filter, c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		user := c.Get("user").(models.User)
+		tx := c.Get("tx").(common.Transaction)
 
-		var response moralisResponse
-		err := fetchFromMoralis(moralisUrl, a.cfg.MoralisApiKey, &response)
+		labels, err := tx.Users().GetAddressLabels(user.ID, c.Request().Context())
 		if err != nil {
 			return err
 		}
+		labelTransactions(page.Transactions, in.Address, labels)
 
 		out := output{
-			Transactions: response.Result,
-			Total:        response.Total,
+			Transactions: page.Transactions,
+			Total:        page.Total,
+			Cursor:       page.Cursor,
 		}
 
-		return c.JSON(http.StatusOK, out)
+		return cacheableJSON(c, out)
 	}
 }
 
 func (a *Api) HandleGetBalance() echo.HandlerFunc {
+	type input struct {
+		Address  string `param:"address" validate:"required,ethereum_address"`
+		Chain    string `query:"chain" validate:"required,chain"`
+		Currency string `query:"currency" validate:"omitempty,oneof=usd eur"`
+	}
+
+	type output struct {
+		Balance string            `json:"balance"`
+		Fiat    *models.FiatValue `json:"fiat,omitempty"`
+	}
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		balance, err := a.chainData.GetBalance(in.Address, a.chainID(in.Chain), c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		out := output{Balance: balance}
+		if in.Currency != "" {
+			chain, _ := a.cfg.Chains.Find(in.Chain)
+			out.Fiat, err = a.fiatValue(chain, balance, in.Currency, c.Request().Context())
+			if err != nil {
+				return err
+			}
+		}
+
+		return cacheableJSON(c, out)
+	}
+}
+
+func (a *Api) HandleGetTokenBalances() echo.HandlerFunc {
 	type input struct {
 		Address string `param:"address" validate:"required,ethereum_address"`
-		Chain   string `query:"chain" validate:"required"`
+		Chain   string `query:"chain" validate:"required,chain"`
+	}
+
+	type output struct {
+		Tokens []models.TokenBalance `json:"tokens"`
+	}
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		tokens, err := a.chainData.GetTokenBalances(in.Address, a.chainID(in.Chain), c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		return cacheableJSON(c, output{tokens})
+	}
+}
+
+func (a *Api) HandleGetTokenTransfers() echo.HandlerFunc {
+	type input struct {
+		Address   string `param:"address" validate:"required,ethereum_address"`
+		Chain     string `query:"chain" validate:"required,chain"`
+		Cursor    string `query:"cursor" validate:"max=4096"`
+		Limit     int64  `query:"limit" validate:"gte=0,lte=100"`
+		FromBlock uint64 `query:"from_block"`
+		ToBlock   uint64 `query:"to_block" validate:"omitempty,gtefield=FromBlock"`
+		FromDate  string `query:"from_date" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		ToDate    string `query:"to_date" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 	}
 
 	type output struct {
-		Balance string `json:"balance"`
+		Transfers []models.TokenTransfer `json:"transfers"`
+		Cursor    string                 `json:"cursor"`
 	}
 	return func(c echo.Context) error {
 		var in input
@@ -91,51 +169,178 @@ func (a *Api) HandleGetBalance() echo.HandlerFunc {
 			return err
 		}
 
-		moralisUrl := fmt.Sprintf("https://deep-index.moralis.io/api/v2/%s/balance?chain=%s", in.Address, in.Chain)
+		filter, err := newPageFilter(in.Cursor, in.Limit, in.FromBlock, in.ToBlock, in.FromDate, in.ToDate)
+		if err != nil {
+			return err
+		}
 
-		var out output
-		err := fetchFromMoralis(moralisUrl, a.cfg.MoralisApiKey, &out)
+		page, err := a.chainData.GetTokenTransfers(in.Address, a.chainID(in.Chain), filter, c.Request().Context())
 		if err != nil {
 			return err
 		}
 
-		return c.JSON(http.StatusOK, out)
+		return cacheableJSON(c, output(page))
 	}
 }
 
-func fetchFromMoralis(url, apiKey string, out any) error {
-	req, err := http.NewRequest("GET", url, nil)
+func (a *Api) HandleGetNFTs() echo.HandlerFunc {
+	type input struct {
+		Address string `param:"address" validate:"required,ethereum_address"`
+		Chain   string `query:"chain" validate:"required,chain"`
+		Cursor  string `query:"cursor" validate:"max=4096"`
+		Limit   int64  `query:"limit" validate:"gte=0,lte=100"`
+	}
+
+	type output struct {
+		NFTs   []models.NFT `json:"nfts"`
+		Cursor string       `json:"cursor"`
+	}
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		filter, err := newPageFilter(in.Cursor, in.Limit, 0, 0, "", "")
+		if err != nil {
+			return err
+		}
+
+		page, err := a.chainData.GetNFTs(in.Address, a.chainID(in.Chain), filter, c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		return cacheableJSON(c, output(page))
+	}
+}
+
+// labelTransactions names the counterparties known to the user and sets the direction relative to the address.
+// The direction stays empty if the address is neither sender nor recipient, e.g. for internal transfers reported by the provider.
+func labelTransactions(transactions []models.Transaction, address string, labels map[string]string) {
+	address = strings.ToLower(address)
+	for i := range transactions {
+		from := strings.ToLower(transactions[i].FromAddress)
+		to := strings.ToLower(transactions[i].ToAddress)
+
+		transactions[i].FromLabel = labels[from]
+		transactions[i].ToLabel = labels[to]
+
+		switch {
+		case from == address && to == address:
+			transactions[i].Direction = models.DirectionSelf
+		case from == address:
+			transactions[i].Direction = models.DirectionOut
+		case to == address:
+			transactions[i].Direction = models.DirectionIn
+		default:
+			transactions[i].Direction = ""
+		}
+	}
+}
+
+// chainID normalizes a validated chain key or id to the hex id used by the ChainDataProvider
+func (a *Api) chainID(chain string) string {
+	cfg, _ := a.cfg.Chains.Find(chain)
+	return cfg.HexID()
+}
+
+// cacheableJSON lets clients revalidate the response instead of downloading it again.
+// The ETag is derived from the body, so unchanged data is answered with 304 Not Modified.
+// No max-age is sent, since the remaining lifetime of the chain data cache entry is unknown here.
+func cacheableJSON(c echo.Context, out interface{}) error {
+	body, err := json.Marshal(out)
 	if err != nil {
-		return fmt.Errorf("failed to instantiate request: %w", err)
+		return fmt.Errorf("failed to encode response: %w", err)
 	}
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("X-API-Key", apiKey)
 
-	res, err := http.DefaultClient.Do(req)
+	hash := sha256.Sum256(body)
+	etag := fmt.Sprintf("\"%s\"", hex.EncodeToString(hash[:16]))
+
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	// Responses depend on the session, so shared caches must not store them
+	header.Set("Cache-Control", "private, no-cache")
+
+	for _, match := range strings.Split(c.Request().Header.Get("If-None-Match"), ",") {
+		if strings.TrimPrefix(strings.TrimSpace(match), "W/") == etag {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+func newPageFilter(cursor string, limit int64, fromBlock, toBlock uint64, fromDate, toDate string) (models.PageFilter, error) {
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+
+	filter := models.PageFilter{
+		Cursor:    cursor,
+		Limit:     limit,
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+	}
+
+	var err error
+	if fromDate != "" {
+		filter.FromDate, err = time.Parse(time.RFC3339, fromDate)
+		if err != nil {
+			return models.PageFilter{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid from_date")
+		}
+	}
+	if toDate != "" {
+		filter.ToDate, err = time.Parse(time.RFC3339, toDate)
+		if err != nil {
+			return models.PageFilter{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid to_date")
+		}
+	}
+
+	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() && filter.ToDate.Before(filter.FromDate) {
+		return models.PageFilter{}, echo.NewHTTPError(http.StatusBadRequest, "to_date must not be before from_date")
+	}
+
+	return filter, nil
+}
+
+// fiatValue converts an amount of the native coin of the chain in wei to the currency
+func (a *Api) fiatValue(chain config.ChainConfig, amount, currency string, ctx context.Context) (*models.FiatValue, error) {
+	value, quote, err := a.exactFiatValue(chain, amount, currency, ctx)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer func() {
-		_ = res.Body.Close()
-	}()
-
-	switch res.StatusCode {
-	case http.StatusBadRequest:
-		var response ErrorResponse
-		if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-			return fmt.Errorf("failed to decode moralis response: %w", err)
-		}
-		return echo.NewHTTPError(http.StatusBadRequest, response.Message)
-	case http.StatusOK:
-		if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
-			return fmt.Errorf("failed to decode moralis response: %w", err)
-		}
-		return nil
-	default:
-		var response ErrorResponse
-		if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-			return fmt.Errorf("failed to decode moralis response: %w", err)
-		}
-		return echo.NewHTTPError(http.StatusBadRequest, response.Message)
+		return nil, err
+	}
+
+	return newFiatValue(value, quote, currency), nil
+}
+
+// exactFiatValue is like fiatValue, but returns the unrounded value and the quote it is based on
+func (a *Api) exactFiatValue(chain config.ChainConfig, amount, currency string, ctx context.Context) (*big.Rat, prices.Quote, error) {
+	if chain.PriceID == "" {
+		return nil, prices.Quote{}, echo.NewHTTPError(http.StatusBadRequest, "Fiat prices are not available for this chain")
+	}
+
+	wei, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return nil, prices.Quote{}, fmt.Errorf("invalid amount %s", amount)
+	}
+
+	quote, err := a.prices.GetQuote(chain.PriceID, currency, ctx)
+	if err != nil {
+		return nil, prices.Quote{}, echo.NewHTTPError(http.StatusServiceUnavailable, "Fiat prices are currently unavailable").SetInternal(err)
+	}
+
+	return prices.Value(wei, nativeDecimals, quote), quote, nil
+}
+
+func newFiatValue(value *big.Rat, quote prices.Quote, currency string) *models.FiatValue {
+	return &models.FiatValue{
+		Currency:  currency,
+		Value:     value.FloatString(2),
+		Price:     quote.Price.FloatString(8),
+		UpdatedAt: quote.UpdatedAt.Unix(),
 	}
 }
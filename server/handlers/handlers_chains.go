@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/labstack/echo/v4"
+	"math/big"
+	"net/http"
+)
+
+func (a *Api) HandleGetChains() echo.HandlerFunc {
+	type chain struct {
+		ID            int64  `json:"id"`
+		HexID         string `json:"hex_id"`
+		Key           string `json:"key"`
+		Name          string `json:"name"`
+		NativeSymbol  string `json:"native_symbol"`
+		ExplorerURL   string `json:"explorer_url"`
+		FaucetEnabled bool   `json:"faucet_enabled"`
+	}
+
+	type output struct {
+		Chains []chain `json:"chains"`
+	}
+	return func(c echo.Context) error {
+		// RPC urls are omitted on purpose, since they may contain provider api keys
+		out := output{
+			Chains: make([]chain, len(a.cfg.Chains)),
+		}
+		for i, cfg := range a.cfg.Chains {
+			out.Chains[i] = chain{
+				ID:            cfg.ID,
+				HexID:         cfg.HexID(),
+				Key:           cfg.Key,
+				Name:          cfg.Name,
+				NativeSymbol:  cfg.NativeSymbol,
+				ExplorerURL:   cfg.ExplorerURL,
+				FaucetEnabled: cfg.FaucetEnabled,
+			}
+		}
+
+		return c.JSON(http.StatusOK, out)
+	}
+}
+
+func (a *Api) HandleGetFees() echo.HandlerFunc {
+	type input struct {
+		Chain string `param:"chain" validate:"required,chain"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		chain, _ := a.cfg.Chains.Find(in.Chain)
+
+		fees, err := a.gas.Fees(chain.ID, c.Request().Context())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadGateway, "Failed to get fee data").SetInternal(err)
+		}
+
+		return c.JSON(http.StatusOK, fees)
+	}
+}
+
+func (a *Api) HandleEstimateGas() echo.HandlerFunc {
+	type input struct {
+		Chain string `param:"chain" validate:"required,chain"`
+		From  string `json:"from" validate:"required,ethereum_address_or_name"`
+		To    string `json:"to" validate:"omitempty,ethereum_address_or_name"`
+		Value string `json:"value" validate:"omitempty,number,max=78"`
+		Data  string `json:"data" validate:"omitempty,hexadecimal,max=262144"`
+	}
+
+	type output struct {
+		Gas uint64 `json:"gas"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		chain, _ := a.cfg.Chains.Find(in.Chain)
+
+		from, err := a.resolveAddress(in.From, c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		msg := ethereum.CallMsg{
+			From: from,
+		}
+		if in.To != "" {
+			to, err := a.resolveAddress(in.To, c.Request().Context())
+			if err != nil {
+				return err
+			}
+			msg.To = &to
+		}
+		if in.Value != "" {
+			msg.Value, _ = new(big.Int).SetString(in.Value, 10)
+		}
+		if in.Data != "" {
+			msg.Data = common.FromHex(in.Data)
+		}
+		if msg.To == nil && len(msg.Data) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Either to or data is required")
+		}
+
+		gas, err := a.gas.EstimateGas(chain.ID, msg, c.Request().Context())
+		if err != nil {
+			// Errors returned by the node, e.g. a reverting call, are caused by the request
+			var rpcErr rpc.Error
+			if errors.As(err, &rpcErr) {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Gas estimation failed: %s", revertReason(err)))
+			}
+			return echo.NewHTTPError(http.StatusBadGateway, "Failed to estimate gas").SetInternal(err)
+		}
+
+		return c.JSON(http.StatusOK, output{gas})
+	}
+}
+
+// revertReason appends the revert data returned by the node, if any
+func revertReason(err error) string {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok && data != "" {
+			if _, decodeErr := hexutil.Decode(data); decodeErr == nil {
+				return fmt.Sprintf("%s (%s)", err.Error(), data)
+			}
+		}
+	}
+	return err.Error()
+}
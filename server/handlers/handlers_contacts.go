@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/Leantar/elonwallet-backend/models"
@@ -42,9 +43,12 @@ func (a *Api) HandleGetContacts() echo.HandlerFunc {
 	}
 }
 
+// HandleCreateContact adds the user with the given email or the owner of the given wallet as a contact.
+// The wallet may also be given as an ENS name.
 func (a *Api) HandleCreateContact() echo.HandlerFunc {
 	type input struct {
-		Email string `json:"email" validate:"required,email"`
+		Email   string `json:"email" validate:"required_without=Address,excluded_with=Address,omitempty,email"`
+		Address string `json:"address" validate:"required_without=Email,omitempty,ethereum_address_or_name"`
 	}
 	return func(c echo.Context) error {
 		var in input
@@ -58,11 +62,16 @@ func (a *Api) HandleCreateContact() echo.HandlerFunc {
 		user := c.Get("user").(models.User)
 		tx := c.Get("tx").(common.Transaction)
 
-		if in.Email == user.Email {
-			return echo.NewHTTPError(http.StatusBadRequest, "You cannot add yourself as a contact")
+		wallet := ""
+		if in.Address != "" {
+			address, err := a.resolveAddress(in.Address, c.Request().Context())
+			if err != nil {
+				return err
+			}
+			wallet = address.Hex()
 		}
 
-		con, err := tx.Users().GetUserByEmail(in.Email, c.Request().Context())
+		con, err := findContact(tx, in.Email, wallet, c.Request().Context())
 		if errors.Is(err, common.ErrNotFound) {
 			return echo.NewHTTPError(http.StatusNotFound)
 		}
@@ -70,6 +79,10 @@ func (a *Api) HandleCreateContact() echo.HandlerFunc {
 			return fmt.Errorf("failed to get contact: %w", err)
 		}
 
+		if con.ID == user.ID {
+			return echo.NewHTTPError(http.StatusBadRequest, "You cannot add yourself as a contact")
+		}
+
 		if con.EnclaveURL == "" { //user has not yet activated his account
 			return echo.NewHTTPError(http.StatusNotFound)
 		}
@@ -121,3 +134,17 @@ func (a *Api) HandleRemoveContact() echo.HandlerFunc {
 		return c.NoContent(http.StatusOK)
 	}
 }
+
+// findContact looks up the user by email or as the owner of the wallet
+func findContact(tx common.Transaction, email, wallet string, ctx context.Context) (models.User, error) {
+	if email != "" {
+		return tx.Users().GetUserByEmail(email, ctx)
+	}
+
+	ownerID, err := tx.Users().GetWalletOwnerID(wallet, ctx)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return tx.Users().GetUserByID(ownerID, ctx)
+}
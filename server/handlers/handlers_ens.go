@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/Leantar/elonwallet-backend/ens"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+func (a *Api) HandleResolveName() echo.HandlerFunc {
+	type input struct {
+		Name string `param:"name" validate:"required,max=255"`
+	}
+
+	type output struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+		if !ens.IsName(in.Name) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid name")
+		}
+
+		address, err := a.ens.Resolve(in.Name, c.Request().Context())
+		if err != nil {
+			return ensError(err)
+		}
+
+		return c.JSON(http.StatusOK, output{
+			Name:    strings.ToLower(in.Name),
+			Address: address.Hex(),
+		})
+	}
+}
+
+func (a *Api) HandleReverseAddress() echo.HandlerFunc {
+	type input struct {
+		Address string `param:"address" validate:"required,ethereum_address"`
+	}
+
+	type output struct {
+		Address string `json:"address"`
+		Name    string `json:"name"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		address := common.HexToAddress(in.Address)
+
+		name, err := a.ens.Reverse(address, c.Request().Context())
+		if err != nil {
+			return ensError(err)
+		}
+
+		return c.JSON(http.StatusOK, output{
+			Address: address.Hex(),
+			Name:    name,
+		})
+	}
+}
+
+// resolveAddress returns the address of an input validated with ethereum_address_or_name, resolving names through ENS
+func (a *Api) resolveAddress(value string, ctx context.Context) (common.Address, error) {
+	if !ens.IsName(value) {
+		return common.HexToAddress(value), nil
+	}
+
+	address, err := a.ens.Resolve(value, ctx)
+	if err != nil {
+		return common.Address{}, ensError(err)
+	}
+
+	return address, nil
+}
+
+func ensError(err error) error {
+	if errors.Is(err, ens.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Name not found")
+	}
+	if errors.Is(err, ens.ErrDisabled) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Name resolution is not available")
+	}
+	return echo.NewHTTPError(http.StatusBadGateway, "Failed to resolve name").SetInternal(err)
+}
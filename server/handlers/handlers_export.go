@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/ethutil"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/prices"
+	"github.com/labstack/echo/v4"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+const (
+	exportPageLimit = 100
+	// The write deadline is extended after every page, since a full history can take longer than the server write timeout
+	exportWriteTimeout = 2 * time.Minute
+	exportStatusHeader = "X-Export-Status"
+	exportComplete     = "complete"
+	exportFailed       = "failed"
+)
+
+var exportColumns = []string{
+	"wallet", "chain", "hash", "block_number", "timestamp", "direction", "from", "from_label", "to", "to_label",
+	"value", "fee", "symbol", "status", "currency", "value_fiat_current", "fee_fiat_current", "price_at",
+}
+
+type exportRow struct {
+	Wallet      string `json:"wallet"`
+	Chain       string `json:"chain"`
+	Hash        string `json:"hash"`
+	BlockNumber string `json:"block_number"`
+	Timestamp   string `json:"timestamp"`
+	Direction   string `json:"direction"`
+	From        string `json:"from"`
+	FromLabel   string `json:"from_label"`
+	To          string `json:"to"`
+	ToLabel     string `json:"to_label"`
+	Value       string `json:"value"`
+	Fee         string `json:"fee"`
+	Symbol      string `json:"symbol"`
+	Status      string `json:"status"`
+	Currency    string `json:"currency,omitempty"`
+	ValueFiat   string `json:"value_fiat_current,omitempty"`
+	FeeFiat     string `json:"fee_fiat_current,omitempty"`
+	PriceAt     string `json:"price_at,omitempty"`
+}
+
+func (r exportRow) record() []string {
+	return []string{
+		r.Wallet, r.Chain, r.Hash, r.BlockNumber, r.Timestamp, r.Direction, r.From, r.FromLabel, r.To, r.ToLabel,
+		r.Value, r.Fee, r.Symbol, r.Status, r.Currency, r.ValueFiat, r.FeeFiat, r.PriceAt,
+	}
+}
+
+// exportWriter writes rows in the requested format directly to the response
+type exportWriter interface {
+	Write(row exportRow) error
+	Flush() error
+	Close() error
+}
+
+type csvExportWriter struct {
+	res *echo.Response
+	w   *csv.Writer
+}
+
+func newCSVExportWriter(res *echo.Response) (*csvExportWriter, error) {
+	writer := &csvExportWriter{res: res, w: csv.NewWriter(res)}
+	return writer, writer.w.Write(exportColumns)
+}
+
+func (c *csvExportWriter) Write(row exportRow) error {
+	return c.w.Write(row.record())
+}
+
+func (c *csvExportWriter) Flush() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	c.res.Flush()
+	return nil
+}
+
+func (c *csvExportWriter) Close() error {
+	return c.Flush()
+}
+
+// jsonExportWriter writes a json array element by element, so that the history is never held in memory
+type jsonExportWriter struct {
+	res   *echo.Response
+	enc   *json.Encoder
+	count int
+}
+
+func newJSONExportWriter(res *echo.Response) (*jsonExportWriter, error) {
+	_, err := res.Write([]byte("["))
+	return &jsonExportWriter{res: res, enc: json.NewEncoder(res)}, err
+}
+
+func (j *jsonExportWriter) Write(row exportRow) error {
+	if j.count > 0 {
+		if _, err := j.res.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	j.count++
+	return j.enc.Encode(row)
+}
+
+func (j *jsonExportWriter) Flush() error {
+	j.res.Flush()
+	return nil
+}
+
+func (j *jsonExportWriter) Close() error {
+	_, err := j.res.Write([]byte("]"))
+	return err
+}
+
+// HandleExportTransactions streams the transaction history of all wallets of the user on all of their chains.
+// Fiat values are calculated with the current price, since no historical prices are available.
+// The columns are named accordingly and price_at holds the time of the price.
+// Errors after the first row has been written cannot change the status code anymore,
+// so clients must check the X-Export-Status trailer for completeness.
+func (a *Api) HandleExportTransactions() echo.HandlerFunc {
+	type input struct {
+		Format   string `query:"format" validate:"omitempty,oneof=csv json"`
+		From     string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		To       string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		Currency string `query:"currency" validate:"omitempty,oneof=usd eur"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+		if in.Format == "" {
+			in.Format = "csv"
+		}
+		if in.Currency == "" {
+			in.Currency = "usd"
+		}
+
+		filter, err := newPageFilter("", exportPageLimit, 0, 0, in.From, in.To)
+		if err != nil {
+			return err
+		}
+
+		user := c.Get("user").(models.User)
+		ctx := c.Request().Context()
+
+		labels, err := a.getAddressLabels(user.ID, ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get address labels: %w", err)
+		}
+
+		res := c.Response()
+		res.Header().Set("Trailer", exportStatusHeader)
+		res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"transactions.%s\"", in.Format))
+		if in.Format == "json" {
+			res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		} else {
+			res.Header().Set(echo.HeaderContentType, "text/csv; charset=UTF-8")
+		}
+		res.WriteHeader(http.StatusOK)
+
+		var writer exportWriter
+		if in.Format == "json" {
+			writer, err = newJSONExportWriter(res)
+		} else {
+			writer, err = newCSVExportWriter(res)
+		}
+		if err == nil {
+			err = a.exportTransactions(res, writer, user.Wallets, labels, filter, in.Currency, ctx)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+
+		status := exportComplete
+		if err != nil {
+			status = exportFailed
+		}
+		res.Header().Set(exportStatusHeader, status)
+		res.Flush()
+
+		if err != nil {
+			return fmt.Errorf("failed to export transactions: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func (a *Api) exportTransactions(res *echo.Response, writer exportWriter, wallets []models.Wallet, labels map[string]string, filter models.PageFilter, currency string, ctx context.Context) error {
+	rc := http.NewResponseController(res)
+
+	for _, wallet := range wallets {
+		for _, chain := range a.walletChains(wallet) {
+			quote, hasQuote := a.exportQuote(chain, currency, ctx)
+
+			pageFilter := filter
+			seen := make(map[string]struct{})
+			for {
+				page, err := a.chainData.GetTransactions(wallet.Address, chain.HexID(), pageFilter, ctx)
+				if err != nil {
+					return fmt.Errorf("failed to get transactions of %s on %s: %w", wallet.Address, chain.Key, err)
+				}
+
+				labelTransactions(page.Transactions, wallet.Address, labels)
+				for _, transaction := range page.Transactions {
+					row := newExportRow(wallet, chain, transaction)
+					if hasQuote {
+						row.addFiat(transaction, quote, currency)
+					}
+					if err := writer.Write(row); err != nil {
+						return err
+					}
+				}
+
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+				_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+				// A repeated cursor would never end, so it is treated like the last page
+				if _, ok := seen[page.Cursor]; page.Cursor == "" || ok {
+					break
+				}
+				seen[page.Cursor] = struct{}{}
+				pageFilter.Cursor = page.Cursor
+			}
+		}
+	}
+
+	return nil
+}
+
+// getAddressLabels reads the labels in a short transaction, since the request transaction is released before the export is streamed
+func (a *Api) getAddressLabels(userID string, ctx context.Context) (map[string]string, error) {
+	tx, err := a.tf.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := tx.Users().GetAddressLabels(userID, ctx)
+	if rbErr := tx.Rollback(); rbErr != nil {
+		return nil, rbErr
+	}
+
+	return labels, err
+}
+
+// exportQuote returns the current price of the native coin of the chain, if available
+func (a *Api) exportQuote(chain config.ChainConfig, currency string, ctx context.Context) (prices.Quote, bool) {
+	if chain.PriceID == "" {
+		return prices.Quote{}, false
+	}
+
+	quote, err := a.prices.GetQuote(chain.PriceID, currency, ctx)
+	return quote, err == nil
+}
+
+func newExportRow(wallet models.Wallet, chain config.ChainConfig, transaction models.Transaction) exportRow {
+	row := exportRow{
+		Wallet:      wallet.Address,
+		Chain:       chain.Key,
+		Hash:        transaction.Hash,
+		BlockNumber: transaction.BlockNumber,
+		Timestamp:   transaction.BlockTimestamp,
+		Direction:   transaction.Direction,
+		From:        transaction.FromAddress,
+		FromLabel:   transaction.FromLabel,
+		To:          transaction.ToAddress,
+		ToLabel:     transaction.ToLabel,
+		Symbol:      chain.NativeSymbol,
+		Status:      transaction.ReceiptStatus,
+	}
+
+	if value, ok := new(big.Int).SetString(transaction.Value, 10); ok {
+		row.Value = ethutil.FormatAmount(value, nativeDecimals)
+	}
+	if fee := transactionFee(transaction); fee != nil {
+		row.Fee = ethutil.FormatAmount(fee, nativeDecimals)
+	}
+
+	return row
+}
+
+func (r *exportRow) addFiat(transaction models.Transaction, quote prices.Quote, currency string) {
+	r.Currency = currency
+	r.PriceAt = quote.UpdatedAt.UTC().Format(time.RFC3339)
+	if value, ok := new(big.Int).SetString(transaction.Value, 10); ok {
+		r.ValueFiat = prices.Value(value, nativeDecimals, quote).FloatString(2)
+	}
+	if fee := transactionFee(transaction); fee != nil {
+		r.FeeFiat = prices.Value(fee, nativeDecimals, quote).FloatString(2)
+	}
+}
+
+// transactionFee returns the fee in wei, or nil if the wallet did not pay it or the provider did not return the receipt data
+func transactionFee(transaction models.Transaction) *big.Int {
+	if transaction.Direction != models.DirectionOut && transaction.Direction != models.DirectionSelf {
+		return nil
+	}
+
+	gasUsed, ok := new(big.Int).SetString(transaction.ReceiptGasUsed, 10)
+	if !ok {
+		return nil
+	}
+	gasPrice, ok := new(big.Int).SetString(transaction.GasPrice, 10)
+	if !ok {
+		return nil
+	}
+	return gasUsed.Mul(gasUsed, gasPrice)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/faucet"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+func (a *Api) HandleFaucetDrip() echo.HandlerFunc {
+	type input struct {
+		Address string `param:"address" validate:"required,ethereum_address"`
+		Chain   string `json:"chain" validate:"required,chain"`
+	}
+
+	type output struct {
+		TxHash  string `json:"tx_hash"`
+		ChainID int64  `json:"chain_id"`
+		Amount  string `json:"amount"`
+	}
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		user := c.Get("user").(models.User)
+
+		if !walletExists(in.Address, user) {
+			return echo.NewHTTPError(http.StatusForbidden, "You are not allowed to access this address")
+		}
+
+		chain, _ := a.cfg.Chains.Find(in.Chain)
+
+		drip, err := a.faucet.Drip(user.ID, in.Address, chain, c.Request().Context())
+		if errors.Is(err, faucet.ErrDisabled) {
+			return echo.NewHTTPError(http.StatusBadRequest, "The faucet is not available for this chain")
+		}
+		if errors.Is(err, faucet.ErrLimitReached) {
+			return echo.NewHTTPError(http.StatusTooManyRequests, "The daily faucet limit has been reached")
+		}
+		if errors.Is(err, faucet.ErrEmpty) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "The faucet is currently empty, please try again later")
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, output{
+			TxHash:  drip.TxHash,
+			ChainID: drip.ChainID,
+			Amount:  drip.Amount,
+		})
+	}
+}
+
+func (a *Api) HandleGetFaucetJob() echo.HandlerFunc {
+	type input struct {
+		ID int64 `param:"id" validate:"required,gt=0"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		user := c.Get("user").(models.User)
+		tx := c.Get("tx").(common.Transaction)
+
+		job, err := tx.Faucet().GetJob(in.ID, user.ID, c.Request().Context())
+		if errors.Is(err, common.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Faucet job not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get faucet job: %w", err)
+		}
+
+		return c.JSON(http.StatusOK, job)
+	}
+}
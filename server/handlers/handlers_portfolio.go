@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Bounds the concurrent provider requests of all portfolio requests together, so that concurrent requests
+	// cannot exhaust the rate limit of the provider
+	portfolioConcurrency = 8
+)
+
+type portfolioJob struct {
+	wallet int
+	chain  int
+	cfg    config.ChainConfig
+}
+
+func (a *Api) HandleGetPortfolio() echo.HandlerFunc {
+	type input struct {
+		Currency string `query:"currency" validate:"omitempty,oneof=usd eur"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		user := c.Get("user").(models.User)
+		ctx := c.Request().Context()
+
+		portfolio := models.Portfolio{
+			Wallets: make([]models.PortfolioWallet, len(user.Wallets)),
+		}
+
+		jobs := make([]portfolioJob, 0)
+		for i, wallet := range user.Wallets {
+			chains := a.walletChains(wallet)
+			portfolio.Wallets[i] = models.PortfolioWallet{
+				Name:    wallet.Name,
+				Address: wallet.Address,
+				Chains:  make([]models.PortfolioChain, len(chains)),
+			}
+			for j, chain := range chains {
+				jobs = append(jobs, portfolioJob{wallet: i, chain: j, cfg: chain})
+			}
+		}
+
+		a.runPortfolioJobs(&portfolio, jobs, ctx)
+
+		portfolio.Totals = sumPortfolio(portfolio.Wallets)
+		for _, wallet := range portfolio.Wallets {
+			for _, chain := range wallet.Chains {
+				if chain.Error != "" {
+					portfolio.Partial = true
+				}
+			}
+		}
+
+		if in.Currency != "" {
+			a.addPortfolioFiat(&portfolio, in.Currency, ctx)
+		}
+
+		return c.JSON(http.StatusOK, portfolio)
+	}
+}
+
+// walletChains returns the configured chains the wallet is used on, or all configured chains if none are tracked
+func (a *Api) walletChains(wallet models.Wallet) []config.ChainConfig {
+	if len(wallet.Chains) == 0 {
+		return a.cfg.Chains
+	}
+
+	chains := make([]config.ChainConfig, 0, len(wallet.Chains))
+	for _, id := range wallet.Chains {
+		if chain, ok := a.cfg.Chains.FindByID(id); ok {
+			chains = append(chains, chain)
+		}
+	}
+
+	return chains
+}
+
+// runPortfolioJobs loads the holdings concurrently, bounded by the slots shared by all requests.
+// Failures are reported per wallet and chain.
+func (a *Api) runPortfolioJobs(portfolio *models.Portfolio, jobs []portfolioJob, ctx context.Context) {
+	wg := sync.WaitGroup{}
+
+	for _, job := range jobs {
+		address := portfolio.Wallets[job.wallet].Address
+
+		select {
+		case a.portfolioSlots <- struct{}{}:
+		case <-ctx.Done():
+			portfolio.Wallets[job.wallet].Chains[job.chain] = models.PortfolioChain{
+				ChainID: job.cfg.ID,
+				Balance: "0",
+				Tokens:  make([]models.TokenBalance, 0),
+				Error:   portfolioError(ctx.Err()),
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(job portfolioJob) {
+			defer wg.Done()
+			defer func() { <-a.portfolioSlots }()
+			// Every job writes to its own slot, so no locking is needed
+			portfolio.Wallets[job.wallet].Chains[job.chain] = a.loadPortfolioChain(address, job.cfg, ctx)
+		}(job)
+	}
+
+	wg.Wait()
+}
+
+func (a *Api) loadPortfolioChain(address string, chain config.ChainConfig, ctx context.Context) models.PortfolioChain {
+	result := models.PortfolioChain{
+		ChainID: chain.ID,
+		Balance: "0",
+		Tokens:  make([]models.TokenBalance, 0),
+	}
+
+	balance, err := a.chainData.GetBalance(address, chain.HexID(), ctx)
+	if err != nil {
+		log.Warn().Caller().Err(err).Str("address", address).Int64("chain_id", chain.ID).Msg("failed to get portfolio balance")
+		result.Error = portfolioError(err)
+		return result
+	}
+	result.Balance = balance
+
+	tokens, err := a.chainData.GetTokenBalances(address, chain.HexID(), ctx)
+	if err != nil {
+		log.Warn().Caller().Err(err).Str("address", address).Int64("chain_id", chain.ID).Msg("failed to get portfolio tokens")
+		result.Error = portfolioError(err)
+		return result
+	}
+	result.Tokens = tokens
+
+	return result
+}
+
+// portfolioError only exposes messages meant for clients
+func portfolioError(err error) string {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		if msg, ok := httpErr.Message.(string); ok {
+			return msg
+		}
+	}
+	return "Failed to load holdings"
+}
+
+func sumPortfolio(wallets []models.PortfolioWallet) []models.PortfolioTotal {
+	type total struct {
+		balance *big.Int
+		tokens  map[string]*models.TokenBalance
+		amounts map[string]*big.Int
+	}
+
+	totals := make(map[int64]*total)
+	for _, wallet := range wallets {
+		for _, chain := range wallet.Chains {
+			t, ok := totals[chain.ChainID]
+			if !ok {
+				t = &total{
+					balance: new(big.Int),
+					tokens:  make(map[string]*models.TokenBalance),
+					amounts: make(map[string]*big.Int),
+				}
+				totals[chain.ChainID] = t
+			}
+
+			if balance, ok := new(big.Int).SetString(chain.Balance, 10); ok {
+				t.balance.Add(t.balance, balance)
+			}
+
+			for _, token := range chain.Tokens {
+				key := strings.ToLower(token.TokenAddress)
+				amount, ok := new(big.Int).SetString(token.Balance, 10)
+				if !ok {
+					continue
+				}
+				if _, ok := t.tokens[key]; !ok {
+					tokenCopy := token
+					t.tokens[key] = &tokenCopy
+					t.amounts[key] = new(big.Int)
+				}
+				t.amounts[key].Add(t.amounts[key], amount)
+			}
+		}
+	}
+
+	result := make([]models.PortfolioTotal, 0, len(totals))
+	for chainID, t := range totals {
+		tokens := make([]models.TokenBalance, 0, len(t.tokens))
+		for key, token := range t.tokens {
+			token.Balance = t.amounts[key].String()
+			tokens = append(tokens, *token)
+		}
+		sort.Slice(tokens, func(i, j int) bool {
+			return tokens[i].TokenAddress < tokens[j].TokenAddress
+		})
+
+		result = append(result, models.PortfolioTotal{
+			ChainID: chainID,
+			Balance: t.balance.String(),
+			Tokens:  tokens,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ChainID < result[j].ChainID
+	})
+
+	return result
+}
+
+// addPortfolioFiat values the native coin totals. Chains without a price are skipped, since testnet coins have no value.
+// The total is summed from the exact values and only rounded once.
+func (a *Api) addPortfolioFiat(portfolio *models.Portfolio, currency string, ctx context.Context) {
+	sum := new(big.Rat)
+	var oldest time.Time
+	priced := false
+
+	for i, total := range portfolio.Totals {
+		chain, ok := a.cfg.Chains.FindByID(total.ChainID)
+		if !ok || chain.PriceID == "" {
+			continue
+		}
+
+		value, quote, err := a.exactFiatValue(chain, total.Balance, currency, ctx)
+		if err != nil {
+			log.Warn().Caller().Err(err).Int64("chain_id", chain.ID).Msg("failed to get portfolio price")
+			portfolio.Partial = true
+			continue
+		}
+		portfolio.Totals[i].Fiat = newFiatValue(value, quote, currency)
+
+		sum.Add(sum, value)
+		if !priced || quote.UpdatedAt.Before(oldest) {
+			oldest = quote.UpdatedAt
+		}
+		priced = true
+	}
+
+	if priced {
+		portfolio.FiatTotal = &models.FiatValue{
+			Currency:  currency,
+			Value:     sum.FloatString(2),
+			UpdatedAt: oldest.Unix(),
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (a *Api) HandleBroadcastTransaction() echo.HandlerFunc {
+	type input struct {
+		RawTx string `json:"raw_tx" validate:"required,hexadecimal,max=262144"`
+	}
+
+	type output struct {
+		Hash    string `json:"hash"`
+		ChainID int64  `json:"chain_id"`
+		Nonce   uint64 `json:"nonce"`
+		Status  string `json:"status"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		user := c.Get("user").(models.User)
+
+		raw := ethcommon.FromHex(in.RawTx)
+		signedTx := new(types.Transaction)
+		if err := signedTx.UnmarshalBinary(raw); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid transaction").SetInternal(err)
+		}
+
+		// Transactions without a chain id could be replayed on every chain
+		if !signedTx.Protected() || signedTx.ChainId().Sign() == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Transaction must be replay protected")
+		}
+		if !signedTx.ChainId().IsInt64() {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unsupported chain")
+		}
+		chain, ok := a.cfg.Chains.FindByID(signedTx.ChainId().Int64())
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unsupported chain")
+		}
+
+		from, err := types.Sender(types.LatestSignerForChainID(signedTx.ChainId()), signedTx)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid transaction signature").SetInternal(err)
+		}
+		if !walletExists(from.Hex(), user) {
+			return echo.NewHTTPError(http.StatusForbidden, "Transaction is not sent from one of your wallets")
+		}
+
+		client, err := a.clients.Get(chain.ID, c.Request().Context())
+		if err != nil {
+			return err
+		}
+
+		to := ""
+		if signedTx.To() != nil {
+			to = signedTx.To().Hex()
+		}
+
+		// The transaction is recorded before it is sent, so that the confirmer can track it even if the request fails afterwards
+		now := time.Now().Unix()
+		transaction, created, err := a.recordRelayedTransaction(models.OutboundTransaction{
+			UserID:  user.ID,
+			ChainID: chain.ID,
+			Hash:    signedTx.Hash().Hex(),
+			Nonce:   signedTx.Nonce(),
+			From:    from.Hex(),
+			To:      to,
+			Value:   signedTx.Value().String(),
+			RawTx:   hexutil.Encode(raw),
+			Status:  models.OutboundStatusPending,
+			Created: now,
+			Updated: now,
+		}, c.Request().Context())
+		if err != nil {
+			return fmt.Errorf("failed to record transaction %s: %w", signedTx.Hash().Hex(), err)
+		}
+
+		// A transaction that was relayed before is not sent again, the confirmer takes care of it
+		if !created {
+			return c.JSON(http.StatusOK, output{
+				Hash:    transaction.Hash,
+				ChainID: transaction.ChainID,
+				Nonce:   transaction.Nonce,
+				Status:  transaction.Status,
+			})
+		}
+
+		err = client.SendTransaction(c.Request().Context(), signedTx)
+		if err != nil && !strings.Contains(err.Error(), "already known") {
+			// Errors returned by the node, e.g. a too low nonce or insufficient funds, are caused by the transaction
+			var rpcErr rpc.Error
+			if errors.As(err, &rpcErr) {
+				dropErr := a.setOutboundTransactionStatus(transaction.Hash, models.OutboundStatusDropped, c.Request().Context())
+				if dropErr != nil {
+					return fmt.Errorf("failed to drop rejected transaction %s: %w", transaction.Hash, dropErr)
+				}
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Transaction was rejected: %s", rpcErr.Error()))
+			}
+
+			// The transaction may have reached the node, so it stays pending and the confirmer rebroadcasts it
+			log.Warn().Caller().Err(err).Str("hash", transaction.Hash).Msg("failed to broadcast relayed transaction")
+		}
+
+		return c.JSON(http.StatusCreated, output{
+			Hash:    transaction.Hash,
+			ChainID: transaction.ChainID,
+			Nonce:   transaction.Nonce,
+			Status:  transaction.Status,
+		})
+	}
+}
+
+func (a *Api) HandleGetPendingTransactions() echo.HandlerFunc {
+	type output struct {
+		Transactions []models.OutboundTransaction `json:"transactions"`
+	}
+
+	return func(c echo.Context) error {
+		user := c.Get("user").(models.User)
+		tx := c.Get("tx").(common.Transaction)
+
+		transactions, err := tx.Outbound().GetOutboundTransactionsOfUser(user.ID, models.OutboundStatusPending, defaultPageLimit, c.Request().Context())
+		if err != nil {
+			return fmt.Errorf("failed to get pending transactions: %w", err)
+		}
+
+		return c.JSON(http.StatusOK, output{transactions})
+	}
+}
+
+// recordRelayedTransaction stores the transaction in its own transaction, so that it is committed before it is broadcast.
+// If the hash is already recorded, the existing transaction is returned and created is false.
+func (a *Api) recordRelayedTransaction(transaction models.OutboundTransaction, ctx context.Context) (models.OutboundTransaction, bool, error) {
+	existing, err := a.getOutboundTransaction(transaction.Hash, ctx)
+	if err == nil {
+		return existing, false, nil
+	} else if !errors.Is(err, common.ErrNotFound) {
+		return models.OutboundTransaction{}, false, err
+	}
+
+	tx, err := a.tf.Begin()
+	if err != nil {
+		return models.OutboundTransaction{}, false, err
+	}
+
+	transaction.ID, err = tx.Outbound().CreateOutboundTransaction(transaction, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return models.OutboundTransaction{}, false, rbErr
+		}
+		// The same transaction was relayed concurrently
+		if errors.Is(err, common.ErrConflict) {
+			existing, err = a.getOutboundTransaction(transaction.Hash, ctx)
+			return existing, false, err
+		}
+		return models.OutboundTransaction{}, false, err
+	}
+
+	return transaction, true, tx.Commit()
+}
+
+func (a *Api) getOutboundTransaction(hash string, ctx context.Context) (models.OutboundTransaction, error) {
+	tx, err := a.tf.Begin()
+	if err != nil {
+		return models.OutboundTransaction{}, err
+	}
+
+	transaction, err := tx.Outbound().GetOutboundTransaction(hash, ctx)
+	if rbErr := tx.Rollback(); rbErr != nil {
+		return models.OutboundTransaction{}, rbErr
+	}
+
+	return transaction, err
+}
+
+func (a *Api) setOutboundTransactionStatus(hash, status string, ctx context.Context) error {
+	tx, err := a.tf.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = tx.Outbound().SetOutboundTransactionStatus(hash, status, time.Now().Unix(), ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -20,9 +20,14 @@ import (
 	"time"
 )
 
+const (
+	challengeTTL = 5 * time.Minute
+)
+
 func (a *Api) HandleAddWalletInitialize() echo.HandlerFunc {
 	type input struct {
 		Address string `json:"address" validate:"required,ethereum_address"`
+		ChainID int64  `json:"chain_id" validate:"omitempty,chain"`
 	}
 
 	type output struct {
@@ -39,20 +44,35 @@ func (a *Api) HandleAddWalletInitialize() echo.HandlerFunc {
 		}
 
 		user := c.Get("user").(models.User)
+		tx := c.Get("tx").(common.Transaction)
+
 		if walletExists(in.Address, user) {
 			return echo.NewHTTPError(http.StatusConflict, "Wallet is already registered")
 		}
 
-		challenge, err := getChallenge()
+		nonce, err := getNonce()
 		if err != nil {
 			return err
 		}
 
-		a.mu.Lock()
-		a.challenges[in.Address] = challenge
-		a.mu.Unlock()
+		if in.ChainID == 0 {
+			in.ChainID = a.defaultChainID()
+		}
+
+		domain, uri := a.siweDomainAndURI()
+		message := newSIWEMessage(domain, uri, in.Address, nonce, in.ChainID, challengeTTL)
 
-		return c.JSON(http.StatusOK, output{Challenge: challenge})
+		err = tx.Challenges().UpsertChallenge(models.Challenge{
+			Address:   in.Address,
+			UserID:    user.ID,
+			Challenge: message.String(),
+			ExpiresAt: message.ExpirationTime.Unix(),
+		}, c.Request().Context())
+		if err != nil {
+			return fmt.Errorf("failed to save challenge: %w", err)
+		}
+
+		return c.JSON(http.StatusOK, output{Challenge: message.String()})
 	}
 }
 
@@ -63,6 +83,10 @@ func (a *Api) HandleAddWalletFinalize() echo.HandlerFunc {
 		Signature string `json:"signature" validate:"required,hexadecimal"`
 	}
 
+	type output struct {
+		FaucetJobID *int64 `json:"faucet_job_id"`
+	}
+
 	return func(c echo.Context) error {
 		var in input
 		if err := c.Bind(&in); err != nil {
@@ -75,15 +99,31 @@ func (a *Api) HandleAddWalletFinalize() echo.HandlerFunc {
 		user := c.Get("user").(models.User)
 		tx := c.Get("tx").(common.Transaction)
 
-		a.mu.Lock()
-		defer a.mu.Unlock()
-		challenge, ok := a.challenges[in.Address]
+		challenge, err := a.consumeChallenge(in.Address, user.ID, c.Request().Context())
+		if errors.Is(err, common.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusConflict, "No challenge requested for this address or the challenge has expired")
+		}
+		if err != nil {
+			return err
+		}
+
+		message, err := parseSIWEMessage(challenge.Challenge)
+		if err != nil {
+			return fmt.Errorf("failed to parse challenge: %w", err)
+		}
+
+		chain, ok := a.cfg.Chains.FindByID(message.ChainID)
 		if !ok {
-			return echo.NewHTTPError(http.StatusConflict, "No challenge requested for this address")
+			return echo.NewHTTPError(http.StatusBadRequest, "Chain of the challenge is no longer supported")
 		}
-		delete(a.challenges, in.Address)
 
-		valid, err := verifyPersonalSignature(challenge, in.Signature, in.Address)
+		domain, uri := a.siweDomainAndURI()
+		err = message.verify(domain, uri, in.Address, chain.ID, time.Now())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid challenge").SetInternal(err)
+		}
+
+		valid, err := a.verifySignature(challenge.Challenge, in.Signature, in.Address, chain, c.Request().Context())
 		if err != nil {
 			return err
 		}
@@ -94,19 +134,116 @@ func (a *Api) HandleAddWalletFinalize() echo.HandlerFunc {
 		err = tx.Users().AddWalletToUser(user.ID, models.Wallet{
 			Name:    in.Name,
 			Address: in.Address,
+			Chains:  []int64{chain.ID},
 		}, c.Request().Context())
 		if err != nil {
 			return fmt.Errorf("failed to add wallet to user: %w", err)
 		}
 
-		if len(user.Wallets) == 0 { //Send some initial MATIC tokens to new users
-			err = sendMumbaiTestMatic(in.Address, a.cfg.Wallet, c.Request().Context())
+		out := output{}
+		if len(user.Wallets) == 0 && chain.FaucetEnabled { //Send some initial test coins to new users
+			// The drip is sent by a worker, so a slow or failing rpc node cannot fail the wallet link
+			job, err := a.faucet.Enqueue(user.ID, in.Address, chain, tx, c.Request().Context())
 			if err != nil {
 				return err
 			}
+			out.FaucetJobID = &job.ID
 		}
 
-		return c.NoContent(http.StatusCreated)
+		return c.JSON(http.StatusCreated, out)
+	}
+}
+
+func (a *Api) HandleGetWallets() echo.HandlerFunc {
+	type output struct {
+		Wallets []models.Wallet `json:"wallets"`
+	}
+
+	return func(c echo.Context) error {
+		user := c.Get("user").(models.User)
+		tx := c.Get("tx").(common.Transaction)
+
+		wallets, err := tx.Users().GetWalletsOfUser(user.ID, c.Request().Context())
+		if err != nil {
+			return fmt.Errorf("failed to get wallets: %w", err)
+		}
+
+		return c.JSON(http.StatusOK, output{wallets})
+	}
+}
+
+func (a *Api) HandleUpdateWallet() echo.HandlerFunc {
+	type input struct {
+		Address string  `param:"address" validate:"required,ethereum_address"`
+		Name    string  `json:"name" validate:"required_without=Chains,omitempty,alphanum"`
+		Chains  []int64 `json:"chains" validate:"required_without=Name,omitempty,gt=0,unique,dive,chain"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		user := c.Get("user").(models.User)
+		tx := c.Get("tx").(common.Transaction)
+
+		if in.Name != "" {
+			err := tx.Users().RenameWallet(user.ID, in.Address, in.Name, c.Request().Context())
+			if errors.Is(err, common.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+			if errors.Is(err, common.ErrConflict) {
+				return echo.NewHTTPError(http.StatusConflict, "A wallet with this name does already exist")
+			}
+			if err != nil {
+				return fmt.Errorf("failed to rename wallet: %w", err)
+			}
+		}
+
+		if len(in.Chains) > 0 {
+			err := tx.Users().SetWalletChains(user.ID, in.Address, in.Chains, c.Request().Context())
+			if errors.Is(err, common.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to set wallet chains: %w", err)
+			}
+		}
+
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+func (a *Api) HandleRemoveWallet() echo.HandlerFunc {
+	type input struct {
+		Address string `param:"address" validate:"required,ethereum_address"`
+	}
+
+	return func(c echo.Context) error {
+		var in input
+		if err := c.Bind(&in); err != nil {
+			return err
+		}
+		if err := c.Validate(&in); err != nil {
+			return err
+		}
+
+		user := c.Get("user").(models.User)
+		tx := c.Get("tx").(common.Transaction)
+
+		err := tx.Users().RemoveWalletFromUser(user.ID, in.Address, c.Request().Context())
+		if errors.Is(err, common.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove wallet: %w", err)
+		}
+
+		return c.NoContent(http.StatusOK)
 	}
 }
 
@@ -476,11 +613,54 @@ func walletExists(address string, user models.User) bool {
 	})
 }
 
-func getChallenge() (string, error) {
-	buf := make([]byte, 32)
+// consumeChallenge removes the challenge in its own transaction, so that it stays consumed even if the finalization fails
+func (a *Api) consumeChallenge(address, userID string, ctx context.Context) (models.Challenge, error) {
+	tx, err := a.tf.Begin()
+	if err != nil {
+		return models.Challenge{}, err
+	}
+
+	challenge, err := tx.Challenges().ConsumeChallenge(address, userID, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return models.Challenge{}, rbErr
+		}
+		return models.Challenge{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Challenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// siweDomainAndURI returns the domain and uri the backend identifies itself with in sign in messages
+func (a *Api) siweDomainAndURI() (string, string) {
+	if a.cfg.UseInsecureHTTP {
+		domain := a.cfg.BackendHost
+		if domain == "" {
+			domain = "localhost:8080"
+		}
+		return domain, fmt.Sprintf("http://%s", domain)
+	}
+
+	return a.cfg.BackendHost, fmt.Sprintf("https://%s", a.cfg.BackendHost)
+}
+
+// defaultChainID is used for wallet challenges if the client does not request a specific chain
+func (a *Api) defaultChainID() int64 {
+	if _, ok := a.cfg.Chains.FindByID(MumbaiChainID); ok {
+		return MumbaiChainID
+	}
+	return a.cfg.Chains[0].ID
+}
+
+func getNonce() (string, error) {
+	buf := make([]byte, 16)
 	_, err := io.ReadFull(rand.Reader, buf)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate challenge: %w", err)
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
 	return hex.EncodeToString(buf), nil
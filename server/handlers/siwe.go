@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// See https://eips.ethereum.org/EIPS/eip-4361 for the message format
+
+const (
+	siweVersion   = "1"
+	siweStatement = "Link this wallet to your ElonWallet account. This request will not trigger a blockchain transaction or cost any gas fees."
+	siweHeader    = " wants you to sign in with your Ethereum account:"
+)
+
+var (
+	siweNonceRegex = regexp.MustCompile("^[a-zA-Z0-9]{8,}$")
+)
+
+type siweMessage struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime time.Time
+}
+
+func newSIWEMessage(domain, uri, address, nonce string, chainID int64, ttl time.Duration) siweMessage {
+	now := time.Now().UTC().Truncate(time.Second)
+	return siweMessage{
+		Domain:         domain,
+		Address:        common.HexToAddress(address).Hex(),
+		Statement:      siweStatement,
+		URI:            uri,
+		Version:        siweVersion,
+		ChainID:        chainID,
+		Nonce:          nonce,
+		IssuedAt:       now,
+		ExpirationTime: now.Add(ttl),
+	}
+}
+
+func (m siweMessage) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("%s%s\n", m.Domain, siweHeader))
+	builder.WriteString(fmt.Sprintf("%s\n\n", m.Address))
+	if m.Statement != "" {
+		builder.WriteString(fmt.Sprintf("%s\n\n", m.Statement))
+	}
+	builder.WriteString(fmt.Sprintf("URI: %s\n", m.URI))
+	builder.WriteString(fmt.Sprintf("Version: %s\n", m.Version))
+	builder.WriteString(fmt.Sprintf("Chain ID: %d\n", m.ChainID))
+	builder.WriteString(fmt.Sprintf("Nonce: %s\n", m.Nonce))
+	builder.WriteString(fmt.Sprintf("Issued At: %s\n", m.IssuedAt.Format(time.RFC3339)))
+	builder.WriteString(fmt.Sprintf("Expiration Time: %s", m.ExpirationTime.Format(time.RFC3339)))
+
+	return builder.String()
+}
+
+func parseSIWEMessage(message string) (siweMessage, error) {
+	lines := strings.Split(message, "\n")
+	if len(lines) < 8 {
+		return siweMessage{}, errors.New("message is too short")
+	}
+
+	var m siweMessage
+
+	domain, ok := strings.CutSuffix(lines[0], siweHeader)
+	if !ok || domain == "" {
+		return siweMessage{}, errors.New("invalid header line")
+	}
+	m.Domain = domain
+	m.Address = lines[1]
+
+	if lines[2] != "" {
+		return siweMessage{}, errors.New("missing empty line after address")
+	}
+
+	rest := lines[3:]
+	if !strings.HasPrefix(rest[0], "URI: ") {
+		if len(rest) < 2 || rest[1] != "" {
+			return siweMessage{}, errors.New("missing empty line after statement")
+		}
+		m.Statement = rest[0]
+		rest = rest[2:]
+	}
+
+	fields := make(map[string]string, len(rest))
+	for _, line := range rest {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return siweMessage{}, fmt.Errorf("invalid field line: %s", line)
+		}
+		fields[key] = value
+	}
+
+	var err error
+	m.URI = fields["URI"]
+	m.Version = fields["Version"]
+	m.Nonce = fields["Nonce"]
+
+	m.ChainID, err = strconv.ParseInt(fields["Chain ID"], 10, 64)
+	if err != nil {
+		return siweMessage{}, fmt.Errorf("invalid chain id: %w", err)
+	}
+
+	m.IssuedAt, err = time.Parse(time.RFC3339, fields["Issued At"])
+	if err != nil {
+		return siweMessage{}, fmt.Errorf("invalid issued at: %w", err)
+	}
+
+	if expiration, ok := fields["Expiration Time"]; ok {
+		m.ExpirationTime, err = time.Parse(time.RFC3339, expiration)
+		if err != nil {
+			return siweMessage{}, fmt.Errorf("invalid expiration time: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+// verify checks every field of the message except the signature
+func (m siweMessage) verify(domain, uri, address string, chainID int64, now time.Time) error {
+	if m.Domain != domain {
+		return fmt.Errorf("domain mismatch: %s", m.Domain)
+	}
+	if m.URI != uri {
+		return fmt.Errorf("uri mismatch: %s", m.URI)
+	}
+	if m.Address != common.HexToAddress(address).Hex() {
+		return fmt.Errorf("address mismatch: %s", m.Address)
+	}
+	if m.Version != siweVersion {
+		return fmt.Errorf("unsupported version: %s", m.Version)
+	}
+	if m.ChainID != chainID {
+		return fmt.Errorf("chain id mismatch: %d", m.ChainID)
+	}
+	if !siweNonceRegex.MatchString(m.Nonce) {
+		return errors.New("invalid nonce")
+	}
+	if now.Before(m.IssuedAt) {
+		return errors.New("message is not yet valid")
+	}
+	if m.ExpirationTime.IsZero() || !now.Before(m.ExpirationTime) {
+		return errors.New("message has expired")
+	}
+
+	return nil
+}
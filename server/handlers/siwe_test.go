@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testDomain  = "backend.example.com"
+	testURI     = "https://backend.example.com"
+	testAddress = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	testNonce   = "a1b2c3d4e5f6g7h8"
+	testChainID = 80001
+)
+
+func TestParseSIWEMessage(t *testing.T) {
+	valid := newSIWEMessage(testDomain, testURI, testAddress, testNonce, testChainID, 5*time.Minute)
+
+	parsed, err := parseSIWEMessage(valid.String())
+	if err != nil {
+		t.Fatalf("failed to parse valid message: %v", err)
+	}
+	if parsed != valid {
+		t.Fatalf("parsed message differs:\n%+v\n%+v", parsed, valid)
+	}
+
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"empty", ""},
+		{"too short", "a\nb\nc"},
+		{"missing header", strings.Replace(valid.String(), siweHeader, " wants you to sign in:", 1)},
+		{"empty domain", strings.Replace(valid.String(), testDomain, "", 1)},
+		{"missing empty line after address", strings.Replace(valid.String(), testAddress+"\n\n", testAddress+"\n", 1)},
+		{"missing empty line after statement", strings.Replace(valid.String(), siweStatement+"\n\n", siweStatement+"\n", 1)},
+		{"invalid field line", strings.Replace(valid.String(), "Version: 1", "Version 1", 1)},
+		{"invalid chain id", strings.Replace(valid.String(), "Chain ID: 80001", "Chain ID: abc", 1)},
+		{"missing chain id", strings.Replace(valid.String(), "Chain ID: 80001\n", "", 1)},
+		{"invalid issued at", strings.Replace(valid.String(), "Issued At: ", "Issued At: yesterday", 1)},
+		{"invalid expiration time", strings.Replace(valid.String(), "Expiration Time: ", "Expiration Time: soon", 1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := parseSIWEMessage(test.message); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestSIWEMessageVerify(t *testing.T) {
+	message := newSIWEMessage(testDomain, testURI, testAddress, testNonce, testChainID, 5*time.Minute)
+	now := message.IssuedAt.Add(time.Minute)
+
+	tests := []struct {
+		name    string
+		modify  func(m *siweMessage)
+		domain  string
+		address string
+		now     time.Time
+		valid   bool
+	}{
+		{name: "valid", valid: true},
+		{name: "lowercase address", address: strings.ToLower(testAddress), valid: true},
+		{name: "wrong domain", domain: "evil.example.com"},
+		{name: "wrong domain in message", modify: func(m *siweMessage) { m.Domain = "evil.example.com" }},
+		{name: "wrong uri", modify: func(m *siweMessage) { m.URI = "https://evil.example.com" }},
+		{name: "wrong address", address: "0x0000000000000000000000000000000000000001"},
+		{name: "unsupported version", modify: func(m *siweMessage) { m.Version = "2" }},
+		{name: "wrong chain", modify: func(m *siweMessage) { m.ChainID = 1 }},
+		{name: "short nonce", modify: func(m *siweMessage) { m.Nonce = "abc" }},
+		{name: "invalid nonce characters", modify: func(m *siweMessage) { m.Nonce = "abcdefgh-ijk" }},
+		{name: "not yet valid", now: message.IssuedAt.Add(-time.Second)},
+		{name: "expired", now: message.ExpirationTime},
+		{name: "missing expiration", modify: func(m *siweMessage) { m.ExpirationTime = time.Time{} }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := message
+			if test.modify != nil {
+				test.modify(&m)
+			}
+			domain, address, at := testDomain, testAddress, now
+			if test.domain != "" {
+				domain = test.domain
+			}
+			if test.address != "" {
+				address = test.address
+			}
+			if !test.now.IsZero() {
+				at = test.now
+			}
+
+			err := m.verify(domain, testURI, address, testChainID, at)
+			if test.valid && err != nil {
+				t.Fatalf("expected message to be valid: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+// consumeChallenge returns the stored challenge and passes ErrNotFound through once there is none.
+// That the stored challenge is deleted on use is up to the repository and is not covered here.
+func TestConsumeChallengePropagatesNotFound(t *testing.T) {
+	message := newSIWEMessage(testDomain, testURI, testAddress, testNonce, testChainID, 5*time.Minute)
+	challenges := &memoryChallenges{challenges: map[string]models.Challenge{
+		"user:" + testAddress: {Address: testAddress, UserID: "user", Challenge: message.String()},
+	}}
+	a := &Api{tf: memoryTransactionFactory{challenges}}
+
+	challenge, err := a.consumeChallenge(testAddress, "user", context.Background())
+	if err != nil {
+		t.Fatalf("failed to consume challenge: %v", err)
+	}
+	if challenge.Challenge != message.String() {
+		t.Fatal("consumed the wrong challenge")
+	}
+
+	_, err = a.consumeChallenge(testAddress, "other", context.Background())
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing challenge, got %v", err)
+	}
+}
+
+type memoryChallenges struct {
+	common.ChallengeRepository
+	challenges map[string]models.Challenge
+}
+
+func (m *memoryChallenges) ConsumeChallenge(address, userID string, _ context.Context) (models.Challenge, error) {
+	key := userID + ":" + address
+	challenge, ok := m.challenges[key]
+	if !ok {
+		return models.Challenge{}, common.ErrNotFound
+	}
+	delete(m.challenges, key)
+	return challenge, nil
+}
+
+type memoryTransaction struct {
+	common.Transaction
+	challenges *memoryChallenges
+}
+
+func (m memoryTransaction) Challenges() common.ChallengeRepository { return m.challenges }
+func (m memoryTransaction) Commit() error                          { return nil }
+func (m memoryTransaction) Rollback() error                        { return nil }
+
+type memoryTransactionFactory struct {
+	challenges *memoryChallenges
+}
+
+func (m memoryTransactionFactory) Begin() (common.Transaction, error) {
+	return memoryTransaction{challenges: m.challenges}, nil
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/ens"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/exp/slices"
+	"net/http"
+	"strings"
+)
+
+const (
+	AccessOwned            = "owned"
+	AccessOwnedAndContacts = "owned_and_contacts"
+	AccessAny              = "any"
+)
+
+// CheckAddressAccess restricts the :address path parameter to addresses the authenticated user may query.
+// ENS names are resolved and replaced by their address before the check.
+// Must be registered after CheckAuthentication.
+func CheckAddressAccess(policy string, resolver *ens.Resolver) echo.MiddlewareFunc {
+	if policy == "" {
+		policy = AccessOwned
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if ens.IsName(c.Param("address")) {
+				if err := resolveAddressParam(c, resolver); err != nil {
+					return err
+				}
+			}
+
+			if policy == AccessAny {
+				return next(c)
+			}
+
+			user := c.Get("user").(models.User)
+			address := strings.ToLower(c.Param("address"))
+
+			owned := slices.ContainsFunc(user.Wallets, func(wallet models.Wallet) bool {
+				return strings.ToLower(wallet.Address) == address
+			})
+			if owned {
+				return next(c)
+			}
+
+			if policy == AccessOwnedAndContacts {
+				tx := c.Get("tx").(common.Transaction)
+
+				ownerID, err := tx.Users().GetWalletOwnerID(address, c.Request().Context())
+				if err != nil && !errors.Is(err, common.ErrNotFound) {
+					return fmt.Errorf("failed to get wallet owner: %w", err)
+				}
+				if err == nil && slices.Contains(user.Contacts, ownerID) {
+					return next(c)
+				}
+			}
+
+			return echo.NewHTTPError(http.StatusForbidden, "You are not allowed to access this address")
+		}
+	}
+}
+
+func resolveAddressParam(c echo.Context, resolver *ens.Resolver) error {
+	address, err := resolver.Resolve(c.Param("address"), c.Request().Context())
+	if errors.Is(err, ens.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "Name does not resolve to an address")
+	}
+	if errors.Is(err, ens.ErrDisabled) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Name resolution is not available")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to resolve name").SetInternal(err)
+	}
+
+	values := c.ParamValues()
+	for i, name := range c.ParamNames() {
+		if name == "address" {
+			values[i] = address.Hex()
+		}
+	}
+	c.SetParamValues(values...)
+
+	return nil
+}
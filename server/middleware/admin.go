@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+// CheckAdmin restricts a route to the users listed in the comma separated admin emails.
+// Must be registered after CheckAuthentication.
+func CheckAdmin(adminEmails string) echo.MiddlewareFunc {
+	admins := make(map[string]struct{})
+	for _, email := range strings.Split(adminEmails, ",") {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email != "" {
+			admins[email] = struct{}{}
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user := c.Get("user").(models.User)
+			if _, ok := admins[strings.ToLower(user.Email)]; !ok {
+				return echo.NewHTTPError(http.StatusForbidden, "Admin access required")
+			}
+
+			return next(c)
+		}
+	}
+}
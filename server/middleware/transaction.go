@@ -18,6 +18,10 @@ func ManageTransaction(tf common.TransactionFactory) echo.MiddlewareFunc {
 			c.Set("tx", tx)
 
 			err = next(c)
+			if c.Get("tx") == nil {
+				// The transaction was released by the route
+				return err
+			}
 			if err != nil {
 				if err := tx.Rollback(); err != nil {
 					log.Fatal().Caller().Err(err).Msg("failed to rollback")
@@ -33,3 +37,20 @@ func ManageTransaction(tf common.TransactionFactory) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// ReleaseTransaction commits the request transaction before the handler runs. It is meant for long running handlers,
+// e.g. streamed responses, which must not hold a database connection. It must be registered after the authentication.
+func ReleaseTransaction() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tx := c.Get("tx").(common.Transaction)
+			c.Set("tx", nil)
+
+			if err := tx.Commit(); err != nil {
+				return fmt.Errorf("failed to commit: %w", err)
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+func (s *Server) workOnOutboundTransactions() {
+	ctx := context.Background()
+	for {
+		time.Sleep(15 * time.Second)
+
+		n, err := s.confirmer.Check(ctx)
+		if err != nil {
+			log.Error().Caller().Err(err).Msg("failed to check outbound transactions")
+			continue
+		}
+
+		if n > 0 {
+			log.Debug().Caller().Int("count", n).Msg("checked outbound transactions")
+		}
+	}
+}
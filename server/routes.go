@@ -6,7 +6,7 @@ import (
 )
 
 func (s *Server) registerRoutes() error {
-	api := handlers.NewApi(s.tf, s.cfg)
+	api := handlers.NewApi(s.tf, s.cfg, s.chainData, s.faucet, s.clients, s.gas, s.prices, s.ens)
 
 	s.echo.POST("/users", api.HandleCreateUser())
 	s.echo.GET("/users/:email/resend-activation-link", api.HandleResendActivationLink())
@@ -15,9 +15,32 @@ func (s *Server) registerRoutes() error {
 	s.echo.GET("/users/:email", api.HandleGetUser(), server.CheckAuthentication("user"))
 	s.echo.POST("/users/my/wallets/initialize", api.HandleAddWalletInitialize(), server.CheckAuthentication("enclave"))
 	s.echo.POST("/users/my/wallets/finalize", api.HandleAddWalletFinalize(), server.CheckAuthentication("enclave"))
+	s.echo.GET("/users/my/wallets", api.HandleGetWallets(), server.CheckAuthentication("user"))
+	s.echo.PATCH("/users/my/wallets/:address", api.HandleUpdateWallet(), server.CheckAuthentication("user"))
+	s.echo.DELETE("/users/my/wallets/:address", api.HandleRemoveWallet(), server.CheckAuthentication("user"))
 
-	s.echo.GET("/:address/balance", api.HandleGetBalance(), server.CheckAuthentication("user"))
-	s.echo.GET("/:address/transactions", api.HandleGetTransactions(), server.CheckAuthentication("user"))
+	s.echo.GET("/users/my/portfolio", api.HandleGetPortfolio(), server.CheckAuthentication("user"))
+	s.echo.GET("/users/my/transactions/export", api.HandleExportTransactions(), server.CheckAuthentication("user"), server.ReleaseTransaction())
+	s.echo.GET("/users/my/transactions/pending", api.HandleGetPendingTransactions(), server.CheckAuthentication("user"))
+	s.echo.POST("/transactions/broadcast", api.HandleBroadcastTransaction(), server.CheckAuthentication("enclave"))
+
+	s.echo.GET("/chains", api.HandleGetChains())
+	s.echo.GET("/chains/:chain/fees", api.HandleGetFees(), server.CheckAuthentication("user", "enclave"))
+	s.echo.POST("/chains/:chain/estimate-gas", api.HandleEstimateGas(), server.CheckAuthentication("user", "enclave"))
+
+	s.echo.GET("/resolve/:name", api.HandleResolveName(), server.CheckAuthentication("user", "enclave"))
+	s.echo.GET("/reverse/:address", api.HandleReverseAddress(), server.CheckAuthentication("user", "enclave"))
+
+	s.echo.GET("/:address/balance", api.HandleGetBalance(), server.CheckAuthentication("user"), server.CheckAddressAccess(s.cfg.AddressAccess, s.ens))
+	s.echo.GET("/:address/transactions", api.HandleGetTransactions(), server.CheckAuthentication("user"), server.CheckAddressAccess(s.cfg.AddressAccess, s.ens))
+	s.echo.GET("/:address/tokens", api.HandleGetTokenBalances(), server.CheckAuthentication("user"), server.CheckAddressAccess(s.cfg.AddressAccess, s.ens))
+	s.echo.GET("/:address/token-transfers", api.HandleGetTokenTransfers(), server.CheckAuthentication("user"), server.CheckAddressAccess(s.cfg.AddressAccess, s.ens))
+	s.echo.GET("/:address/nfts", api.HandleGetNFTs(), server.CheckAuthentication("user"), server.CheckAddressAccess(s.cfg.AddressAccess, s.ens))
+
+	s.echo.POST("/faucet/:address", api.HandleFaucetDrip(), server.CheckAuthentication("user"))
+	s.echo.GET("/faucet/jobs/:id", api.HandleGetFaucetJob(), server.CheckAuthentication("user"))
+
+	s.echo.GET("/admin/outbound-transactions", api.HandleGetOutboundTransactions(), server.CheckAuthentication("user"), server.CheckAdmin(s.cfg.AdminEmails))
 
 	s.echo.GET("/contacts", api.HandleGetContacts(), server.CheckAuthentication("user"))
 	s.echo.POST("/contacts", api.HandleCreateContact(), server.CheckAuthentication("user"))
@@ -3,9 +3,17 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/ens"
+	"github.com/Leantar/elonwallet-backend/faucet"
+	"github.com/Leantar/elonwallet-backend/gas"
+	"github.com/Leantar/elonwallet-backend/prices"
 	"github.com/Leantar/elonwallet-backend/server/common"
 	customMiddleware "github.com/Leantar/elonwallet-backend/server/middleware"
+	"github.com/Leantar/elonwallet-backend/signer"
+	"github.com/Leantar/elonwallet-backend/txmanager"
+	"github.com/Leantar/elonwallet-backend/watcher"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/rs/zerolog/log"
@@ -16,18 +24,57 @@ import (
 )
 
 type Server struct {
-	echo   *echo.Echo
-	cfg    config.Config
-	tf     common.TransactionFactory
-	tlsMgr *autocert.Manager
+	echo      *echo.Echo
+	cfg       config.Config
+	tf        common.TransactionFactory
+	chainData common.ChainDataProvider
+	faucet    *faucet.Service
+	clients   *txmanager.ClientPool
+	gas       *gas.Oracle
+	prices    *prices.Service
+	confirmer *txmanager.Confirmer
+	watcher   *watcher.Watcher
+	ens       *ens.Resolver
+	tlsMgr    *autocert.Manager
 }
 
-func New(cfg config.Config, tf common.TransactionFactory) (*Server, error) {
+func New(cfg config.Config, tf common.TransactionFactory, chainData common.ChainDataProvider) (*Server, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	walletSigner, err := signer.New(cfg.Wallet, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+
+	priceSource, err := prices.NewSource(cfg.Prices)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create price source: %w", err)
+	}
+
+	// Names are resolved on mainnet, whose registry entry is used unless a dedicated node is configured
+	ensURL := cfg.ENSRPCURL
+	if mainnet, ok := cfg.Chains.FindByID(1); ensURL == "" && ok {
+		ensURL = mainnet.RPCURL
+	}
+
+	clients := txmanager.NewClientPool(cfg.Chains)
+	nonces := txmanager.NewNonceManager(tf, walletSigner)
+	f := faucet.NewService(cfg, tf, clients, nonces)
+
 	e := echo.New()
 	s := &Server{
-		echo: e,
-		cfg:  cfg,
-		tf:   tf,
+		echo:      e,
+		cfg:       cfg,
+		tf:        tf,
+		chainData: chainData,
+		faucet:    f,
+		clients:   clients,
+		gas:       gas.NewOracle(clients),
+		prices:    prices.NewService(priceSource, time.Duration(cfg.Prices.MaxAgeSeconds)*time.Second),
+		confirmer: txmanager.NewConfirmer(tf, nonces, clients),
+		watcher:   watcher.New(tf, clients),
+		ens:       ens.NewResolver(ensURL, clients),
 	}
 
 	if cfg.UseInsecureHTTP {
@@ -66,7 +113,7 @@ func New(cfg config.Config, tf common.TransactionFactory) (*Server, error) {
 		e.TLSServer.Addr = "0.0.0.0:8443"
 	}
 
-	cv := newValidator()
+	cv := newValidator(cfg.Chains)
 	e.Binder = &BinderWithURLDecoding{&echo.DefaultBinder{}}
 	e.Validator = &cv
 
@@ -85,6 +132,10 @@ func (s *Server) Run() (err error) {
 	}
 
 	go s.workOnNotifications(s.cfg.Email)
+	go s.workOnExpiredChallenges()
+	go s.workOnOutboundTransactions()
+	go s.workOnFaucetJobs()
+	go s.workOnIncomingTransfers()
 
 	if s.cfg.UseInsecureHTTP {
 		log.Info().Caller().Msgf("http server started on %s", s.echo.Server.Addr)
@@ -1,10 +1,13 @@
 package server
 
 import (
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/ens"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"reflect"
 	"regexp"
 )
 
@@ -29,16 +32,32 @@ func (c *CustomValidator) Validate(i interface{}) error {
 	return nil
 }
 
-func newValidator() CustomValidator {
+func newValidator(chains config.Chains) CustomValidator {
 	v := CustomValidator{
 		validator: validator.New(),
 	}
 
 	_ = v.validator.RegisterValidation("ethereum_address", ValidateEthereumAddress, false)
+	_ = v.validator.RegisterValidation("ethereum_address_or_name", ValidateEthereumAddressOrName, false)
+	_ = v.validator.RegisterValidation("chain", newChainValidation(chains), false)
 
 	return v
 }
 
+// newChainValidation accepts chain keys and ids which are present in the chain registry
+func newChainValidation(chains config.Chains) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		var ok bool
+		switch fl.Field().Kind() {
+		case reflect.String:
+			_, ok = chains.Find(fl.Field().String())
+		case reflect.Int, reflect.Int64:
+			_, ok = chains.FindByID(fl.Field().Int())
+		}
+		return ok
+	}
+}
+
 func ValidateEthereumAddress(fl validator.FieldLevel) (valid bool) {
 	value := fl.Field().String()
 
@@ -53,3 +72,9 @@ func ValidateEthereumAddress(fl validator.FieldLevel) (valid bool) {
 
 	return
 }
+
+// ValidateEthereumAddressOrName additionally accepts ENS names. Names are only checked for their format,
+// handlers resolve them before use.
+func ValidateEthereumAddressOrName(fl validator.FieldLevel) bool {
+	return ValidateEthereumAddress(fl) || ens.IsName(fl.Field().String())
+}
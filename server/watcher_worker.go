@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+func (s *Server) workOnIncomingTransfers() {
+	ctx := context.Background()
+	for {
+		time.Sleep(15 * time.Second)
+
+		for _, chain := range s.cfg.Chains {
+			if !chain.WatchIncoming {
+				continue
+			}
+
+			n, err := s.watcher.Poll(chain, ctx)
+			if err != nil {
+				log.Error().Caller().Err(err).Int64("chain_id", chain.ID).Msg("failed to watch incoming transfers")
+				continue
+			}
+
+			if n > 0 {
+				log.Debug().Caller().Uint64("blocks", n).Int64("chain_id", chain.ID).Msg("watched incoming transfers")
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"os"
+	"strings"
+)
+
+// LocalSigner holds the private key in memory
+type LocalSigner struct {
+	privateKey *ecdsa.PrivateKey
+	address    common.Address
+}
+
+// NewRawSigner uses an unencrypted hex encoded private key
+func NewRawSigner(privateKeyHex string) (*LocalSigner, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert hex to private key: %w", err)
+	}
+
+	return newLocalSigner(privateKey), nil
+}
+
+// NewKeystoreSigner decrypts a go-ethereum keystore file. The passphrase is read from a file, e.g. a mounted secret.
+func NewKeystoreSigner(keystoreFile, passphraseFile string) (*LocalSigner, error) {
+	keyJSON, err := os.ReadFile(keystoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %w", err)
+	}
+
+	passphrase, err := os.ReadFile(passphraseFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase file: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, strings.TrimRight(string(passphrase), "\r\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore file: %w", err)
+	}
+
+	return newLocalSigner(key.PrivateKey), nil
+}
+
+func newLocalSigner(privateKey *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{
+		privateKey: privateKey,
+		address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+	}
+}
+
+func (l *LocalSigner) Address() common.Address {
+	return l.address
+}
+
+func (l *LocalSigner) SignTx(tx *types.Transaction, _ context.Context) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(tx.ChainId()), l.privateKey)
+}
@@ -0,0 +1,107 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RemoteSigner delegates signing to an external signer such as Clef, so the key never enters this process.
+// See https://geth.ethereum.org/docs/tools/clef/apis for the api.
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+type remoteTxArgs struct {
+	From                 common.MixedcaseAddress  `json:"from"`
+	To                   *common.MixedcaseAddress `json:"to"`
+	Gas                  hexutil.Uint64           `json:"gas"`
+	MaxFeePerGas         *hexutil.Big             `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big             `json:"maxPriorityFeePerGas"`
+	Value                hexutil.Big              `json:"value"`
+	Nonce                hexutil.Uint64           `json:"nonce"`
+	Input                hexutil.Bytes            `json:"input"`
+	ChainID              *hexutil.Big             `json:"chainId"`
+}
+
+type remoteSignResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+func NewRemoteSigner(url, address string, ctx context.Context) (*RemoteSigner, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial remote signer: %w", err)
+	}
+
+	return &RemoteSigner{
+		client:  client,
+		address: common.HexToAddress(address),
+	}, nil
+}
+
+func (r *RemoteSigner) Address() common.Address {
+	return r.address
+}
+
+func (r *RemoteSigner) SignTx(tx *types.Transaction, ctx context.Context) (*types.Transaction, error) {
+	if tx.Type() != types.DynamicFeeTxType {
+		return nil, fmt.Errorf("unsupported tx type %d", tx.Type())
+	}
+
+	args := remoteTxArgs{
+		From:                 common.NewMixedcaseAddress(r.address),
+		Gas:                  hexutil.Uint64(tx.Gas()),
+		MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap()),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap()),
+		Value:                hexutil.Big(*tx.Value()),
+		Nonce:                hexutil.Uint64(tx.Nonce()),
+		Input:                tx.Data(),
+		ChainID:              (*hexutil.Big)(tx.ChainId()),
+	}
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+
+	var result remoteSignResult
+	err := r.client.CallContext(ctx, &result, "account_signTransaction", args)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer failed: %w", err)
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(result.Raw); err != nil {
+		return nil, fmt.Errorf("failed to decode signed tx: %w", err)
+	}
+
+	// The remote signer may modify the transaction, e.g. through rules, so the result must match what was requested
+	if err := verifySigned(tx, signed, r.address); err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+func verifySigned(tx, signed *types.Transaction, address common.Address) error {
+	sender, err := types.Sender(types.LatestSignerForChainID(signed.ChainId()), signed)
+	if err != nil {
+		return fmt.Errorf("failed to recover signer: %w", err)
+	}
+	if sender != address {
+		return fmt.Errorf("tx was signed by %s", sender.Hex())
+	}
+
+	unsignedHash := types.LatestSignerForChainID(tx.ChainId()).Hash(tx)
+	signedHash := types.LatestSignerForChainID(signed.ChainId()).Hash(signed)
+	if unsignedHash != signedHash {
+		return errors.New("remote signer returned a different tx")
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TypeRaw      = "raw"
+	TypeKeystore = "keystore"
+	TypeRemote   = "remote"
+)
+
+// Signer signs transactions of the backend funding wallet
+type Signer interface {
+	Address() common.Address
+	SignTx(tx *types.Transaction, ctx context.Context) (*types.Transaction, error)
+}
+
+// New creates the signer selected by the wallet config and checks that it controls the configured address
+func New(cfg config.WalletConfig, ctx context.Context) (Signer, error) {
+	var (
+		s   Signer
+		err error
+	)
+
+	switch cfg.Signer {
+	case TypeKeystore:
+		s, err = NewKeystoreSigner(cfg.KeystoreFile, cfg.PassphraseFile)
+	case TypeRemote:
+		s, err = NewRemoteSigner(cfg.RemoteSignerURL, cfg.Address, ctx)
+	case TypeRaw:
+		log.Warn().Caller().Msg("funding wallet uses a raw private key, which should only be done in development")
+		s, err = NewRawSigner(cfg.PrivateKeyHex)
+	case "":
+		return nil, fmt.Errorf("no signer type configured, set WALLET_SIGNER to %s, %s or %s", TypeKeystore, TypeRemote, TypeRaw)
+	default:
+		return nil, fmt.Errorf("unknown signer type %s", cfg.Signer)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Address() != common.HexToAddress(cfg.Address) {
+		return nil, fmt.Errorf("signer does not belong to wallet address %s", cfg.Address)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,51 @@
+package txmanager
+
+import (
+	"context"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"sync"
+)
+
+// ClientPool shares one rpc client per rpc url
+type ClientPool struct {
+	chains  config.Chains
+	clients map[string]*ethclient.Client
+	mu      sync.Mutex
+}
+
+func NewClientPool(chains config.Chains) *ClientPool {
+	return &ClientPool{
+		chains:  chains,
+		clients: make(map[string]*ethclient.Client),
+	}
+}
+
+// Get returns the client of the rpc node of a configured chain
+func (p *ClientPool) Get(chainID int64, ctx context.Context) (*ethclient.Client, error) {
+	chain, ok := p.chains.FindByID(chainID)
+	if !ok {
+		return nil, fmt.Errorf("chain %d is not configured", chainID)
+	}
+
+	return p.GetByURL(chain.RPCURL, ctx)
+}
+
+// GetByURL returns the client of an rpc node that is not part of the chain registry, e.g. a dedicated node
+func (p *ClientPool) GetByURL(url string, ctx context.Context) (*ethclient.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, ok := p.clients[url]; ok {
+		return client, nil
+	}
+
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial rpc: %w", err)
+	}
+	p.clients[url] = client
+
+	return client, nil
+}
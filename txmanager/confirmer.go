@@ -0,0 +1,358 @@
+package txmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const (
+	confirmerBatchSize = 50
+	// A pending transaction is checked at most once per interval, even with several replicas
+	confirmerCheckInterval = 30 * time.Second
+	// A transaction that is still in the mempool after this duration is replaced with bumped fees
+	stuckAfter = 5 * time.Minute
+	// After this many rebroadcasts or replacements a transaction is given up
+	maxAttempts = 5
+)
+
+// Confirmer tracks the receipts of outbound transactions and rebroadcasts dropped or stuck ones
+type Confirmer struct {
+	tf      common.TransactionFactory
+	nonces  *NonceManager
+	clients *ClientPool
+}
+
+func NewConfirmer(tf common.TransactionFactory, nonces *NonceManager, clients *ClientPool) *Confirmer {
+	return &Confirmer{
+		tf:      tf,
+		nonces:  nonces,
+		clients: clients,
+	}
+}
+
+// writeFunc stores additional results of a check in the transaction that updates the outbound transaction
+type writeFunc func(tx common.Transaction) error
+
+// Check processes one batch of pending transactions and returns the number of processed transactions.
+// The batch is claimed in a short transaction. The node is asked without a transaction open,
+// and the result of every transaction is stored in its own transaction.
+func (c *Confirmer) Check(ctx context.Context) (int, error) {
+	now := time.Now()
+	pending, err := c.claim(now, ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim pending transactions: %w", err)
+	}
+
+	dropped := make(map[int64]struct{})
+	for _, transaction := range pending {
+		write, err := c.check(&transaction, now, ctx)
+		if err != nil {
+			log.Error().Caller().Err(err).Str("hash", transaction.Hash).Int64("chain_id", transaction.ChainID).Msg("failed to check outbound transaction")
+		}
+
+		transaction.Updated = now.Unix()
+		err = c.store(transaction, write, ctx)
+		if errors.Is(err, common.ErrNotFound) {
+			// The transaction was settled meanwhile, e.g. because a competing transaction was mined
+			continue
+		}
+		if err != nil {
+			log.Error().Caller().Err(err).Str("hash", transaction.Hash).Int64("chain_id", transaction.ChainID).Msg("failed to update outbound transaction")
+			continue
+		}
+
+		if transaction.Status == models.OutboundStatusDropped && transaction.UserID == "" {
+			dropped[transaction.ChainID] = struct{}{}
+		}
+	}
+
+	// A dropped transaction leaves a gap in the persisted nonce that would block all later transactions
+	for chainID := range dropped {
+		if err := c.resync(chainID, ctx); err != nil {
+			log.Error().Caller().Err(err).Int64("chain_id", chainID).Msg("failed to resync nonce")
+		}
+	}
+
+	return len(pending), nil
+}
+
+func (c *Confirmer) claim(now time.Time, ctx context.Context) ([]models.OutboundTransaction, error) {
+	tx, err := c.tf.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := tx.Outbound().ClaimPendingOutboundTransactions(now.Add(-confirmerCheckInterval).Unix(), now.Unix(), confirmerBatchSize, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return nil, rbErr
+		}
+		return nil, err
+	}
+
+	return pending, tx.Commit()
+}
+
+// store updates the outbound transaction and runs write in the same transaction
+func (c *Confirmer) store(transaction models.OutboundTransaction, write writeFunc, ctx context.Context) error {
+	tx, err := c.tf.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = tx.Outbound().UpdateOutboundTransaction(transaction, ctx)
+	if err == nil && write != nil {
+		err = write(tx)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// check updates status, block number and attempts of the transaction and returns what else has to be stored.
+// Replacements compete with the original for the same nonce, so all of them stay pending until one is mined.
+func (c *Confirmer) check(transaction *models.OutboundTransaction, now time.Time, ctx context.Context) (writeFunc, error) {
+	client, err := c.clients.Get(transaction.ChainID, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := ethcommon.HexToHash(transaction.Hash)
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	if err == nil {
+		transaction.BlockNumber = receipt.BlockNumber.Uint64()
+		transaction.Status = models.OutboundStatusConfirmed
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			transaction.Status = models.OutboundStatusFailed
+		}
+		settled := *transaction
+		return func(tx common.Transaction) error {
+			return settle(tx, settled, now, ctx)
+		}, nil
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return nil, fmt.Errorf("failed to get receipt: %w", err)
+	}
+
+	minedNonce, err := client.NonceAt(ctx, ethcommon.HexToAddress(transaction.From), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	if minedNonce > transaction.Nonce {
+		// Another transaction with the same nonce was mined. If it is one of ours, checking it settles the nonce.
+		transaction.Status = models.OutboundStatusReplaced
+		return nil, nil
+	}
+
+	// Only the newest transaction of a nonce is rebroadcast or replaced, older ones are only checked for a receipt
+	newest, err := c.isNewest(*transaction, ctx)
+	if err != nil || !newest {
+		return nil, err
+	}
+
+	if transaction.Attempts >= maxAttempts {
+		transaction.Status = models.OutboundStatusDropped
+		return failDrips(transaction.Hash, "The transaction was dropped", now, ctx), nil
+	}
+
+	signedTx, err := decodeTransaction(transaction.RawTx)
+	if err != nil {
+		transaction.Status = models.OutboundStatusDropped
+		return failDrips(transaction.Hash, "The transaction was dropped", now, ctx), err
+	}
+
+	_, _, err = client.TransactionByHash(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		transaction.Attempts++
+		err = client.SendTransaction(ctx, signedTx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebroadcast tx: %w", err)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tx: %w", err)
+	}
+
+	// Relayed transactions are signed by the user, so they can only be rebroadcast but not replaced
+	if transaction.UserID != "" || now.Sub(time.Unix(transaction.Created, 0)) < stuckAfter {
+		return nil, nil
+	}
+
+	return nil, c.replace(client, transaction, signedTx, now, ctx)
+}
+
+// replace records a replacement with bumped fees before it is sent. If sending fails, the next check rebroadcasts it.
+func (c *Confirmer) replace(client *ethclient.Client, transaction *models.OutboundTransaction, signedTx *types.Transaction, now time.Time, ctx context.Context) error {
+	replacement, err := c.nonces.SignReplacement(client, signedTx, ctx)
+	if err != nil {
+		transaction.Attempts++
+		return err
+	}
+
+	tx, err := c.tf.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = recordReplacement(tx, *transaction, replacement, now, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	err = client.SendTransaction(ctx, replacement)
+	if err != nil {
+		return fmt.Errorf("failed to send replacement tx: %w", err)
+	}
+
+	return nil
+}
+
+// isNewest reports whether no later transaction competes for the nonce of the transaction
+func (c *Confirmer) isNewest(transaction models.OutboundTransaction, ctx context.Context) (bool, error) {
+	tx, err := c.tf.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	competing, err := tx.Outbound().GetPendingOutboundTransactionsByNonce(transaction.ChainID, transaction.From, transaction.Nonce, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return false, rbErr
+		}
+		return false, fmt.Errorf("failed to get competing transactions: %w", err)
+	}
+
+	return len(competing) == 0 || competing[len(competing)-1].ID == transaction.ID, tx.Commit()
+}
+
+// settle marks the other pending transactions of the mined nonce as replaced and points the drips to the mined transaction.
+// Drips and jobs of a reverted transaction are failed.
+func settle(tx common.Transaction, transaction models.OutboundTransaction, now time.Time, ctx context.Context) error {
+	competing, err := tx.Outbound().GetPendingOutboundTransactionsByNonce(transaction.ChainID, transaction.From, transaction.Nonce, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get competing transactions: %w", err)
+	}
+
+	replaced := make([]string, 0, len(competing))
+	for _, other := range competing {
+		if other.ID == transaction.ID {
+			continue
+		}
+		replaced = append(replaced, other.Hash)
+
+		err = tx.Outbound().SetOutboundTransactionStatus(other.Hash, models.OutboundStatusReplaced, now.Unix(), ctx)
+		if err != nil {
+			return fmt.Errorf("failed to mark %s as replaced: %w", other.Hash, err)
+		}
+	}
+
+	if len(replaced) > 0 {
+		err = tx.Faucet().ReplaceDripTransaction(replaced, transaction.Hash, ctx)
+		if err != nil {
+			return fmt.Errorf("failed to update drip transaction: %w", err)
+		}
+	}
+
+	if transaction.Status == models.OutboundStatusFailed {
+		return failDrips(transaction.Hash, "The transaction failed", now, ctx)(tx)
+	}
+
+	return tx.Faucet().SetDripStatusByTransaction(transaction.Hash, models.FaucetDripConfirmed, ctx)
+}
+
+// failDrips fails the drips and jobs of a transaction that will never be mined successfully
+func failDrips(hash, reason string, now time.Time, ctx context.Context) writeFunc {
+	return func(tx common.Transaction) error {
+		err := tx.Faucet().SetDripStatusByTransaction(hash, models.FaucetDripFailed, ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fail drips: %w", err)
+		}
+
+		err = tx.Faucet().FailJobsByTransaction(hash, reason, now.Unix(), ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fail faucet jobs: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// recordReplacement stores the replacement. The original stays pending, since it may still be mined instead.
+func recordReplacement(tx common.Transaction, original models.OutboundTransaction, replacement *types.Transaction, now time.Time, ctx context.Context) error {
+	raw, err := replacement.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode tx: %w", err)
+	}
+
+	_, err = tx.Outbound().CreateOutboundTransaction(models.OutboundTransaction{
+		ChainID:  original.ChainID,
+		Hash:     replacement.Hash().Hex(),
+		Nonce:    replacement.Nonce(),
+		From:     original.From,
+		To:       original.To,
+		Value:    replacement.Value().String(),
+		RawTx:    hexutil.Encode(raw),
+		Status:   models.OutboundStatusPending,
+		Attempts: original.Attempts + 1,
+		Created:  now.Unix(),
+		Updated:  now.Unix(),
+	}, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record replacement %s: %w", replacement.Hash().Hex(), err)
+	}
+
+	// Drips follow the newest transaction, so that they are failed if it is dropped
+	err = tx.Faucet().ReplaceDripTransaction([]string{original.Hash}, replacement.Hash().Hex(), ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update drip transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Confirmer) resync(chainID int64, ctx context.Context) error {
+	client, err := c.clients.Get(chainID, ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.nonces.Resync(client, chainID, ctx)
+}
+
+func decodeTransaction(raw string) (*types.Transaction, error) {
+	data, err := hexutil.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode raw tx: %w", err)
+	}
+
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal raw tx: %w", err)
+	}
+
+	return &tx, nil
+}
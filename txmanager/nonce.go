@@ -0,0 +1,304 @@
+package txmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/Leantar/elonwallet-backend/signer"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Nodes only accept a replacement transaction if both fee caps increase by at least 10%
+	feeBumpNumerator   = 1125
+	feeBumpDenominator = 1000
+)
+
+var (
+	ErrRejected = errors.New("transaction rejected by node")
+)
+
+// BuildFunc creates the unsigned transaction for the allocated nonce
+type BuildFunc func(nonce uint64) (*types.Transaction, error)
+
+// NonceManager allocates nonces for a single sending address. The last used nonce is persisted per chain,
+// and the row lock in the database serializes allocation across replicas.
+type NonceManager struct {
+	tf      common.TransactionFactory
+	address ethcommon.Address
+	signer  signer.Signer
+	locks   map[int64]*sync.Mutex
+	mu      sync.Mutex
+}
+
+func NewNonceManager(tf common.TransactionFactory, signer signer.Signer) *NonceManager {
+	return &NonceManager{
+		tf:      tf,
+		address: signer.Address(),
+		signer:  signer,
+		locks:   make(map[int64]*sync.Mutex),
+	}
+}
+
+func (n *NonceManager) Address() ethcommon.Address {
+	return n.address
+}
+
+// Prepare allocates the next nonce, builds and signs the transaction and records it as pending within tx.
+// The nonce row stays locked until tx ends. tx must be committed before the transaction is passed to Broadcast,
+// so that nothing is ever sent without a record that the confirmer can track.
+func (n *NonceManager) Prepare(tx common.Transaction, client *ethclient.Client, chainID int64, build BuildFunc, ctx context.Context) (*types.Transaction, error) {
+	nonce, err := n.lockNextNonce(tx, client, chainID, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key may also be used outside the backend, so the chain always takes precedence if it is ahead
+	pending, err := client.PendingNonceAt(ctx, n.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+	if pending > nonce {
+		nonce = pending
+	}
+
+	unsignedTx, err := build(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	signedTx, err := n.signer.SignTx(unsignedTx, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign tx: %w", err)
+	}
+
+	err = n.record(tx, signedTx, chainID, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record outbound transaction: %w", err)
+	}
+
+	err = tx.Nonces().SetNextNonce(n.address.Hex(), chainID, nonce+1, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to persist nonce: %w", err)
+	}
+
+	return signedTx, nil
+}
+
+// Broadcast sends a transaction that was prepared and committed before.
+// A transaction the node rejects is marked dropped and the nonce is resynced, which is reported as ErrRejected.
+// Any other error leaves the transaction pending, since it may have reached the node. The confirmer then rebroadcasts it.
+func (n *NonceManager) Broadcast(client *ethclient.Client, chainID int64, signedTx *types.Transaction, ctx context.Context) error {
+	err := client.SendTransaction(ctx, signedTx)
+	if err == nil || strings.Contains(err.Error(), "already known") {
+		return nil
+	}
+
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return fmt.Errorf("failed to send tx: %w", err)
+	}
+
+	dropErr := n.drop(client, chainID, signedTx, ctx)
+	if dropErr != nil {
+		log.Error().Caller().Err(dropErr).Str("hash", signedTx.Hash().Hex()).Msg("failed to drop rejected transaction")
+	}
+
+	return fmt.Errorf("%w: %s", ErrRejected, err.Error())
+}
+
+// SignReplacement signs a transaction with the same nonce and fee caps bumped high enough to replace the pending one.
+// It is not sent, so that it can be recorded first.
+func (n *NonceManager) SignReplacement(client *ethclient.Client, tx *types.Transaction, ctx context.Context) (*types.Transaction, error) {
+	tipCap := bumpFee(tx.GasTipCap())
+	feeCap := bumpFee(tx.GasFeeCap())
+
+	suggestedTip, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest tip cap: %w", err)
+	}
+	if suggestedTip.Cmp(tipCap) > 0 {
+		tipCap = suggestedTip
+	}
+
+	suggestedFee, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest fee cap: %w", err)
+	}
+	if suggestedFee.Cmp(feeCap) > 0 {
+		feeCap = suggestedFee
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		feeCap = tipCap
+	}
+
+	replacement := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   tx.ChainId(),
+		Nonce:     tx.Nonce(),
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap,
+		Gas:       tx.Gas(),
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Data:      tx.Data(),
+	})
+
+	signedTx, err := n.signer.SignTx(replacement, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign replacement tx: %w", err)
+	}
+
+	return signedTx, nil
+}
+
+// Resync moves the persisted nonce to the pending nonce reported by the chain, see resync
+func (n *NonceManager) Resync(client *ethclient.Client, chainID int64, ctx context.Context) error {
+	lock := n.chainLock(chainID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	tx, err := n.tf.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = n.lockNextNonce(tx, client, chainID, ctx)
+	if err == nil {
+		err = n.resync(tx, client, chainID, ctx)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// drop gives up a rejected transaction and resyncs the nonce, so that the next transaction fills the gap
+func (n *NonceManager) drop(client *ethclient.Client, chainID int64, signedTx *types.Transaction, ctx context.Context) error {
+	lock := n.chainLock(chainID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	tx, err := n.tf.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = n.lockNextNonce(tx, client, chainID, ctx)
+	if err == nil {
+		err = tx.Outbound().SetOutboundTransactionStatus(signedTx.Hash().Hex(), models.OutboundStatusDropped, time.Now().Unix(), ctx)
+	}
+	if err == nil {
+		err = n.resync(tx, client, chainID, ctx)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// record stores the signed transaction so that the confirmer can track it
+func (n *NonceManager) record(tx common.Transaction, signedTx *types.Transaction, chainID int64, ctx context.Context) error {
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode tx: %w", err)
+	}
+
+	to := ""
+	if signedTx.To() != nil {
+		to = signedTx.To().Hex()
+	}
+
+	now := time.Now().Unix()
+	_, err = tx.Outbound().CreateOutboundTransaction(models.OutboundTransaction{
+		ChainID: chainID,
+		Hash:    signedTx.Hash().Hex(),
+		Nonce:   signedTx.Nonce(),
+		From:    n.address.Hex(),
+		To:      to,
+		Value:   signedTx.Value().String(),
+		RawTx:   hexutil.Encode(raw),
+		Status:  models.OutboundStatusPending,
+		Created: now,
+		Updated: now,
+	}, ctx)
+	return err
+}
+
+func (n *NonceManager) lockNextNonce(tx common.Transaction, client *ethclient.Client, chainID int64, ctx context.Context) (uint64, error) {
+	nonce, err := tx.Nonces().LockNextNonce(n.address.Hex(), chainID, ctx)
+	if !errors.Is(err, common.ErrNotFound) {
+		return nonce, err
+	}
+
+	pending, err := client.PendingNonceAt(ctx, n.address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	err = tx.Nonces().InitNextNonce(n.address.Hex(), chainID, pending, ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to init nonce: %w", err)
+	}
+
+	return tx.Nonces().LockNextNonce(n.address.Hex(), chainID, ctx)
+}
+
+// resync moves the persisted nonce to the pending nonce of the chain. It is never moved below a pending recorded transaction,
+// since prepared transactions may not have been broadcast yet and would collide with new ones.
+func (n *NonceManager) resync(tx common.Transaction, client *ethclient.Client, chainID int64, ctx context.Context) error {
+	pending, err := client.PendingNonceAt(ctx, n.address)
+	if err != nil {
+		return fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	highest, err := tx.Outbound().GetHighestPendingNonce(chainID, n.address.Hex(), ctx)
+	if err != nil && !errors.Is(err, common.ErrNotFound) {
+		return fmt.Errorf("failed to get highest pending nonce: %w", err)
+	}
+	if err == nil && highest >= pending {
+		pending = highest + 1
+	}
+
+	return tx.Nonces().SetNextNonce(n.address.Hex(), chainID, pending, ctx)
+}
+
+func (n *NonceManager) chainLock(chainID int64) *sync.Mutex {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	lock, ok := n.locks[chainID]
+	if !ok {
+		lock = &sync.Mutex{}
+		n.locks[chainID] = lock
+	}
+
+	return lock
+}
+
+func bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(feeBumpNumerator))
+	bumped.Div(bumped, big.NewInt(feeBumpDenominator))
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, big.NewInt(1))
+	}
+	return bumped
+}
@@ -0,0 +1,452 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Leantar/elonwallet-backend/config"
+	"github.com/Leantar/elonwallet-backend/ethutil"
+	"github.com/Leantar/elonwallet-backend/models"
+	"github.com/Leantar/elonwallet-backend/server/common"
+	"github.com/Leantar/elonwallet-backend/txmanager"
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Blocks are only processed once they are this deep, so that reorgs do not cause wrong notifications
+	confirmations    = 5
+	maxBlocksPerPoll = 100
+	// Transfer logs are filtered by recipient, with this many addresses per request
+	addressesPerFilter = 100
+	// Incomplete token metadata is looked up again after this duration
+	fallbackTTL    = 10 * time.Minute
+	nativeDecimals = 18
+	erc20ABIJSON   = `[
+		{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"type":"function"},
+		{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"type":"function"}
+	]`
+)
+
+var (
+	erc20ABI      = ethutil.MustParseABI(erc20ABIJSON)
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+type transfer struct {
+	to       ethcommon.Address
+	from     ethcommon.Address
+	amount   *big.Int
+	token    *ethcommon.Address
+	txHash   ethcommon.Hash
+	logIndex uint
+}
+
+type tokenMetadata struct {
+	symbol   string
+	decimals int64
+	// expires is set for the fallback, so that the lookup is retried after a transient error
+	expires time.Time
+}
+
+// Watcher notifies users about transfers to their wallets
+type Watcher struct {
+	tf      common.TransactionFactory
+	clients *txmanager.ClientPool
+	tokens  map[string]tokenMetadata
+	// windows holds the number of blocks per poll of each chain, which shrinks when the provider rejects the range
+	windows map[int64]uint64
+	mu      sync.Mutex
+}
+
+func New(tf common.TransactionFactory, clients *txmanager.ClientPool) *Watcher {
+	return &Watcher{
+		tf:      tf,
+		clients: clients,
+		tokens:  make(map[string]tokenMetadata),
+		windows: make(map[int64]uint64),
+	}
+}
+
+// Poll processes the next confirmed blocks of the chain and returns the number of processed blocks.
+// The blocks are fetched without holding the cursor lock. The notifications and the cursor are committed together,
+// but only if the cursor has not moved meanwhile, so a restart or another replica neither misses nor duplicates transfers.
+func (w *Watcher) Poll(chain config.ChainConfig, ctx context.Context) (uint64, error) {
+	client, err := w.clients.Get(chain.ID, ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block number: %w", err)
+	}
+	if head < confirmations {
+		return 0, nil
+	}
+	safe := head - confirmations
+
+	last, owners, err := w.state(chain.ID, ctx)
+	if errors.Is(err, common.ErrNotFound) {
+		// Transfers before the watcher was enabled are not notified
+		return 0, w.initCursor(chain.ID, safe, ctx)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if last >= safe {
+		return 0, nil
+	}
+
+	from := last + 1
+	to := safe
+	if window := w.window(chain.ID); to-from+1 > window {
+		to = from + window - 1
+	}
+
+	notifications := make([]models.Notification, 0)
+	if len(owners) > 0 {
+		var transfers []transfer
+		transfers, to, err = w.collectTransfers(client, chain.ID, from, to, owners, ctx)
+		if err != nil {
+			return 0, err
+		}
+		notifications = w.notifications(client, chain, transfers, owners, ctx)
+	}
+
+	tx, err := w.tf.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	processed, err := w.commitInTx(tx, chain.ID, last, to, notifications, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return 0, rbErr
+		}
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	if processed > 0 {
+		w.growWindow(chain.ID)
+	}
+
+	return processed, nil
+}
+
+// state returns the cursor and the owners of the wallets used on the chain
+func (w *Watcher) state(chainID int64, ctx context.Context) (uint64, map[string]string, error) {
+	tx, err := w.tf.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	last, err := tx.Watcher().GetCursor(chainID, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return 0, nil, rbErr
+		}
+		return 0, nil, err
+	}
+
+	owners, err := tx.Users().GetWalletOwnersOnChain(chainID, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return 0, nil, rbErr
+		}
+		return 0, nil, err
+	}
+
+	return last, owners, tx.Commit()
+}
+
+func (w *Watcher) initCursor(chainID int64, lastBlock uint64, ctx context.Context) error {
+	tx, err := w.tf.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = tx.Watcher().InitCursor(chainID, lastBlock, ctx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return fmt.Errorf("failed to init cursor: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// commitInTx stores the notifications and advances the cursor to the given block.
+// Nothing is stored if another replica holds the cursor or has already processed the blocks.
+func (w *Watcher) commitInTx(tx common.Transaction, chainID int64, last, to uint64, notifications []models.Notification, ctx context.Context) (uint64, error) {
+	current, err := tx.Watcher().LockCursor(chainID, ctx)
+	if errors.Is(err, common.ErrConflict) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if current != last {
+		return 0, nil
+	}
+
+	if len(notifications) > 0 {
+		err = tx.Notifications().CreateNotificationSeries(notifications, ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create notifications: %w", err)
+		}
+		log.Debug().Caller().Int("count", len(notifications)).Int64("chain_id", chainID).Msg("enqueued incoming transfer notifications")
+	}
+
+	err = tx.Watcher().SetCursor(chainID, to, ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set cursor: %w", err)
+	}
+
+	return to - last, nil
+}
+
+func (w *Watcher) window(chainID int64) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if window, ok := w.windows[chainID]; ok {
+		return window
+	}
+	return maxBlocksPerPoll
+}
+
+func (w *Watcher) shrinkWindow(chainID int64, window uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.windows[chainID] = window
+}
+
+// growWindow doubles the window after a successful poll, so that a temporary rejection does not slow the chain down forever
+func (w *Watcher) growWindow(chainID int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	window, ok := w.windows[chainID]
+	if !ok {
+		return
+	}
+	window *= 2
+	if window >= maxBlocksPerPoll {
+		delete(w.windows, chainID)
+		return
+	}
+	w.windows[chainID] = window
+}
+
+// collectTransfers returns the transfers to the watched wallets and the last block they were collected up to.
+// If the provider rejects the range of the log filter, the range is halved until it is accepted.
+func (w *Watcher) collectTransfers(client *ethclient.Client, chainID int64, from, to uint64, owners map[string]string, ctx context.Context) ([]transfer, uint64, error) {
+	watched := make([]ethcommon.Address, 0, len(owners))
+	for address := range owners {
+		watched = append(watched, ethcommon.HexToAddress(address))
+	}
+
+	var (
+		logs []types.Log
+		err  error
+	)
+	for {
+		logs, err = filterTransferLogs(client, from, to, watched, ctx)
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && to > from {
+			to = from + (to-from)/2
+			w.shrinkWindow(chainID, to-from+1)
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		break
+	}
+
+	transfers := make([]transfer, 0)
+	signer := types.LatestSignerForChainID(big.NewInt(chainID))
+
+	for number := from; number <= to; number++ {
+		block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get block %d: %w", number, err)
+		}
+
+		for _, tx := range block.Transactions() {
+			if tx.To() == nil || tx.Value().Sign() <= 0 {
+				continue
+			}
+			if _, ok := owners[strings.ToLower(tx.To().Hex())]; !ok {
+				continue
+			}
+
+			sender, err := types.Sender(signer, tx)
+			if err != nil {
+				continue
+			}
+
+			transfers = append(transfers, transfer{
+				to:     *tx.To(),
+				from:   sender,
+				amount: tx.Value(),
+				txHash: tx.Hash(),
+			})
+		}
+	}
+
+	for _, l := range logs {
+		// ERC-721 transfers share the event signature but index the token id
+		if len(l.Topics) != 3 || len(l.Data) != 32 || l.Removed {
+			continue
+		}
+
+		token := l.Address
+		transfers = append(transfers, transfer{
+			to:       ethcommon.BytesToAddress(l.Topics[2].Bytes()),
+			from:     ethcommon.BytesToAddress(l.Topics[1].Bytes()),
+			amount:   new(big.Int).SetBytes(l.Data),
+			token:    &token,
+			txHash:   l.TxHash,
+			logIndex: l.Index,
+		})
+	}
+
+	return transfers, to, nil
+}
+
+// filterTransferLogs returns the transfer logs to the watched addresses, which are filtered in chunks to keep requests small
+func filterTransferLogs(client *ethclient.Client, from, to uint64, watched []ethcommon.Address, ctx context.Context) ([]types.Log, error) {
+	logs := make([]types.Log, 0)
+
+	for start := 0; start < len(watched); start += addressesPerFilter {
+		end := start + addressesPerFilter
+		if end > len(watched) {
+			end = len(watched)
+		}
+
+		recipients := make([]ethcommon.Hash, 0, end-start)
+		for _, address := range watched[start:end] {
+			recipients = append(recipients, ethcommon.BytesToHash(address.Bytes()))
+		}
+
+		chunk, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Topics:    [][]ethcommon.Hash{{transferTopic}, nil, recipients},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter transfer logs: %w", err)
+		}
+		logs = append(logs, chunk...)
+	}
+
+	return logs, nil
+}
+
+func (w *Watcher) notifications(client *ethclient.Client, chain config.ChainConfig, transfers []transfer, owners map[string]string, ctx context.Context) []models.Notification {
+	now := time.Now().Unix()
+	notifications := make([]models.Notification, 0)
+	for _, t := range transfers {
+		userID, ok := owners[strings.ToLower(t.to.Hex())]
+		if !ok || t.amount.Sign() <= 0 {
+			continue
+		}
+
+		symbol, decimals := chain.NativeSymbol, int64(nativeDecimals)
+		seriesID := fmt.Sprintf("incoming-%d-%s", chain.ID, t.txHash.Hex())
+		if t.token != nil {
+			metadata := w.tokenMetadata(client, chain.ID, *t.token, ctx)
+			symbol, decimals = metadata.symbol, metadata.decimals
+			seriesID = fmt.Sprintf("%s-%d", seriesID, t.logIndex)
+		}
+
+		body := fmt.Sprintf("Your wallet %s received %s %s from %s on %s.", t.to.Hex(), ethutil.FormatAmount(t.amount, decimals), symbol, t.from.Hex(), chain.Name)
+		if chain.ExplorerURL != "" {
+			body += fmt.Sprintf("\n\n%s/tx/%s", strings.TrimSuffix(chain.ExplorerURL, "/"), t.txHash.Hex())
+		}
+
+		notifications = append(notifications, models.Notification{
+			SeriesID:     seriesID,
+			CreationTime: now,
+			SendAfter:    now,
+			UserID:       userID,
+			Title:        fmt.Sprintf("You received %s", symbol),
+			Body:         body,
+		})
+	}
+
+	return notifications
+}
+
+// tokenMetadata falls back to the token address if the token does not implement the optional metadata methods.
+// Only complete metadata is cached for good, the fallback is looked up again after fallbackTTL.
+func (w *Watcher) tokenMetadata(client *ethclient.Client, chainID int64, token ethcommon.Address, ctx context.Context) tokenMetadata {
+	key := fmt.Sprintf("%d:%s", chainID, token.Hex())
+	now := time.Now()
+
+	w.mu.Lock()
+	metadata, ok := w.tokens[key]
+	w.mu.Unlock()
+	if ok && (metadata.expires.IsZero() || now.Before(metadata.expires)) {
+		return metadata
+	}
+
+	metadata = tokenMetadata{symbol: token.Hex()}
+	complete := true
+
+	var symbol string
+	if err := callContract(client, token, "symbol", &symbol, ctx); err == nil && symbol != "" {
+		metadata.symbol = symbol
+	} else {
+		complete = false
+	}
+
+	var decimals uint8
+	if err := callContract(client, token, "decimals", &decimals, ctx); err == nil {
+		metadata.decimals = int64(decimals)
+	} else {
+		complete = false
+	}
+
+	if !complete {
+		metadata.expires = now.Add(fallbackTTL)
+	}
+
+	w.mu.Lock()
+	w.tokens[key] = metadata
+	w.mu.Unlock()
+
+	return metadata
+}
+
+func callContract(client *ethclient.Client, contract ethcommon.Address, method string, out interface{}, ctx context.Context) error {
+	data, err := erc20ABI.Pack(method)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return err
+	}
+
+	return erc20ABI.UnpackIntoInterface(out, method, result)
+}